@@ -0,0 +1,270 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// apkArchToGo map Alpine architectures to nfpm (Go) architecture names, other names are the same
+var apkArchToGo = map[string]string{
+	"noarch":  "all",
+	"x86":     "386",
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"armhf":   "arm6",
+	"armv7":   "arm7",
+}
+
+// apkScripts is a apk control files with install scripts
+var apkScripts = []string{".pre-install", ".post-install", ".pre-deinstall", ".post-deinstall", ".pre-upgrade", ".post-upgrade"}
+
+// apkField is a .PKGINFO field (fields like depend may be repeated)
+type apkField struct {
+	key   string
+	value string
+}
+
+// parsePkgInfo parse apk .PKGINFO, continuation lines (with leading space) are joined with '\n'
+func parsePkgInfo(data []byte) ([]apkField, error) {
+	var fields []apkField
+	for n, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(fields) == 0 {
+				return nil, fmt.Errorf(".PKGINFO:%d: unexpected continuation line", n+1)
+			}
+			fields[len(fields)-1].value += "\n" + strings.TrimSpace(line)
+			continue
+		}
+		i := strings.IndexByte(line, '=')
+		if i < 1 {
+			return nil, fmt.Errorf(".PKGINFO:%d: field is invalid: %s", n+1, line)
+		}
+		fields = append(fields, apkField{key: strings.TrimSpace(line[:i]), value: strings.TrimSpace(line[i+1:])})
+	}
+	return fields, nil
+}
+
+// splitApkVersion split apk version VERSION[-rRELEASE]
+func splitApkVersion(s string) (version, release string) {
+	n := strings.LastIndex(s, "-r")
+	if n == -1 || n+2 == len(s) || strings.Trim(s[n+2:], "0123456789") != "" {
+		return s, ""
+	}
+	return s[:n], s[n+2:]
+}
+
+// apkRelation convert apk dependency (like name>=1.0 or !name) to fpm syntax, conflict is set for '!' dependencies
+func apkRelation(dep string) (rel string, conflict bool) {
+	if strings.HasPrefix(dep, "!") {
+		dep, conflict = dep[1:], true
+	}
+	n := strings.IndexAny(dep, "<>=~")
+	if n == -1 {
+		return dep, conflict
+	}
+	version := strings.TrimLeft(dep[n:], "<>=~")
+	return dep[:n] + " " + dep[n:len(dep)-len(version)] + " " + version, conflict
+}
+
+// importApkRelation translate apk dependency to output package syntax, empty string is returned for untranslated
+func (p *Packager) importApkRelation(field, dep string) (string, bool, error) {
+	if p.OutputType == APK {
+		return strings.TrimPrefix(dep, "!"), strings.HasPrefix(dep, "!"), nil
+	}
+	rel, conflict := apkRelation(dep)
+	if strings.Contains(rel, ":") || strings.HasPrefix(rel, "/") {
+		// so:, cmd:, pc: or file providers
+		p.untranslated("apk field %s = %s is not supported for %s", field, dep, p.OutputType.String())
+		return "", false, nil
+	}
+	if strings.Contains(rel, " ~ ") {
+		p.untranslated("apk field %s = %s is translated as >= (fuzzy version match is supported only for apk)", field, dep)
+		rel = strings.Replace(rel, " ~ ", " >= ", 1)
+	}
+	s, err := formatRelation(rel, p.OutputType)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", field, err)
+	}
+	return s, conflict, nil
+}
+
+// importPkgInfo import apk .PKGINFO fields to package info, fields from command line are not overwritten
+func (p *Packager) importPkgInfo(data []byte) error {
+	fields, err := parsePkgInfo(data)
+	if err != nil {
+		return err
+	}
+	var packager string
+	for _, f := range fields {
+		switch f.key {
+		case "pkgname":
+			setIfEmpty(&p.Info.Name, f.value)
+		case "pkgver":
+			if p.Info.Version == "" {
+				version, release := splitApkVersion(f.value)
+				if p.OutputType == RPM && strings.Contains(version, "-") {
+					p.untranslated("apk field pkgver = %s is translated as %s (rpm version can't contain '-')", version, strings.ReplaceAll(version, "-", "_"))
+					version = strings.ReplaceAll(version, "-", "_")
+				}
+				p.Info.Version = version
+				setIfEmpty(&p.Info.Release, release)
+			}
+		case "arch":
+			value := f.value
+			if arch, ok := apkArchToGo[value]; ok {
+				value = arch
+			}
+			setIfEmpty(&p.Info.Arch, value)
+		case "pkgdesc":
+			setIfEmpty(&p.Info.Description, f.value)
+		case "url":
+			setIfEmpty(&p.Info.Homepage, f.value)
+		case "maintainer":
+			setIfEmpty(&p.Info.Maintainer, f.value)
+		case "packager":
+			packager = f.value
+		case "license":
+			setIfEmpty(&p.Info.License, f.value)
+		case "depend", "provides", "replaces":
+			rel, conflict, err := p.importApkRelation(f.key, f.value)
+			if err != nil {
+				return err
+			}
+			switch {
+			case rel == "":
+			case conflict:
+				p.Info.Conflicts = append(p.Info.Conflicts, rel)
+			case f.key == "depend":
+				p.Info.Depends = append(p.Info.Depends, rel)
+			case f.key == "provides":
+				p.Info.Provides = append(p.Info.Provides, rel)
+			default:
+				p.Info.Replaces = append(p.Info.Replaces, rel)
+			}
+		case "size", "builddate", "datahash":
+			// calculated by packager
+		case "origin":
+			if f.value != p.Info.Name {
+				p.untranslated("apk field %s is not supported", f.key)
+			}
+		default:
+			p.untranslated("apk field %s is not supported", f.key)
+		}
+	}
+	// packager is used when maintainer is not set
+	setIfEmpty(&p.Info.Maintainer, packager)
+	return nil
+}
+
+// importApkScripts stage apk install scripts, scripts from command line are not overwritten
+func (p *Packager) importApkScripts(control map[string][]byte) error {
+	scripts := map[string]*string{
+		".pre-install":    &p.Info.Scripts.PreInstall,
+		".post-install":   &p.Info.Scripts.PostInstall,
+		".pre-deinstall":  &p.Info.Scripts.PreRemove,
+		".post-deinstall": &p.Info.Scripts.PostRemove,
+		".pre-upgrade":    &p.Info.APK.Scripts.PreUpgrade,
+		".post-upgrade":   &p.Info.APK.Scripts.PostUpgrade,
+	}
+	var maintScripts bool
+	for _, name := range apkScripts {
+		data, ok := control[name]
+		if !ok {
+			continue
+		}
+		if (name == ".pre-upgrade" || name == ".post-upgrade") && p.OutputType != APK {
+			p.untranslated("apk script %s is not supported for %s", name, p.OutputType.String())
+			continue
+		}
+		if *scripts[name] != "" {
+			// set from command line
+			continue
+		}
+		var err error
+		if *scripts[name], err = p.stage(bytes.NewReader(data), time.Time{}); err != nil {
+			return err
+		}
+		maintScripts = true
+	}
+	if maintScripts && p.OutputType != APK {
+		p.untranslated("apk scripts are imported as is, check them for apk specific environment")
+	}
+	if _, ok := control[".trigger"]; ok {
+		p.untranslated("apk script .trigger is not supported")
+	}
+	return nil
+}
+
+// isApkControl check for apk control file (signature, .PKGINFO or script)
+func isApkControl(name string) bool {
+	if name == ".PKGINFO" || name == ".trigger" || strings.HasPrefix(name, ".SIGN.") {
+		return true
+	}
+	for _, script := range apkScripts {
+		if name == script {
+			return true
+		}
+	}
+	return false
+}
+
+// addApk add files and metadata from apk package
+func (p *Packager) addApk(fm FileMap) error {
+	return p.addArchives(fm, p.readApk)
+}
+
+// readApk read apk package, signature, control and data gzip streams are read as one tar stream
+// (signature and control tars are written without end of archive)
+func (p *Packager) readApk(name string, a *archiveImporter) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := decompress(f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	control := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if isApkControl(cleanEntryName(hdr.Name)) && hdr.Typeflag == tar.TypeReg {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			control[cleanEntryName(hdr.Name)] = data
+			continue
+		}
+		if err = readTarEntry(tr, hdr, a); err != nil {
+			return err
+		}
+	}
+
+	data, ok := control[".PKGINFO"]
+	if !ok {
+		return fmt.Errorf(".PKGINFO not found")
+	}
+	if err = p.importPkgInfo(data); err != nil {
+		return err
+	}
+	return p.importApkScripts(control)
+}
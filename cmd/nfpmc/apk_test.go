@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_splitApkVersion(t *testing.T) {
+	tests := []struct {
+		version           string
+		upstream, release string
+	}{
+		{version: "1.0", upstream: "1.0"},
+		{version: "1.0-r2", upstream: "1.0", release: "2"},
+		{version: "1.0_rc1-r0", upstream: "1.0_rc1", release: "0"},
+		{version: "1.0-rc1", upstream: "1.0-rc1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			upstream, release := splitApkVersion(tt.version)
+			assert.Equal(t, tt.upstream, upstream)
+			assert.Equal(t, tt.release, release)
+		})
+	}
+}
+
+func Test_apkRelation(t *testing.T) {
+	tests := []struct {
+		dep      string
+		want     string
+		conflict bool
+	}{
+		{dep: "musl", want: "musl"},
+		{dep: "musl>=1.2", want: "musl >= 1.2"},
+		{dep: "musl<2", want: "musl < 2"},
+		{dep: "musl~1.2", want: "musl ~ 1.2"},
+		{dep: "!test-old", want: "test-old", conflict: true},
+		{dep: "!test-old<1.0", want: "test-old < 1.0", conflict: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dep, func(t *testing.T) {
+			rel, conflict := apkRelation(tt.dep)
+			assert.Equal(t, tt.want, rel)
+			assert.Equal(t, tt.conflict, conflict)
+		})
+	}
+}
+
+// buildTestApk build apk package with nfpmc
+func buildTestApk(t *testing.T, dir string) string {
+	srcDir := path.Join(dir, "src")
+	for name, data := range map[string]string{
+		"etc/test/test.conf": "key = value\n",
+		"usr/bin/test":       "#!/bin/sh\n",
+		"scripts/postinst":   "#!/bin/sh\necho installed\n",
+		"scripts/upgrade":    "#!/bin/sh\necho upgraded\n",
+	} {
+		name = path.Join(srcDir, name)
+		require.NoError(t, os.MkdirAll(path.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(data), 0755))
+	}
+	require.NoError(t, os.Symlink("test", path.Join(srcDir, "usr/bin/test-link")))
+
+	p := Packager{OutputType: APK, OutDir: dir, Dir: srcDir}
+	p.Info.Name = "test"
+	p.Info.Version = "1.2.3"
+	p.Info.Release = "4"
+	p.Info.Arch = "arm64"
+	p.Info.Maintainer = "Test <test@example.com>"
+	p.Info.Description = "test package\nlong description"
+	p.Info.Homepage = "https://example.com"
+	p.Info.License = "MIT"
+	p.Info.Scripts.PostInstall = path.Join(srcDir, "scripts/postinst")
+	p.PostUpgrade = path.Join(srcDir, "scripts/upgrade")
+
+	require.NoError(t, p.Init())
+	require.NoError(t, p.AddFiles(StringSlice{"etc/=/etc/", "usr/=/usr/"}))
+	require.NoError(t, p.SetDepends(StringSlice{"musl >= 1.2", "so:libc.musl-aarch64.so.1"}))
+	require.NoError(t, p.SetProvides(StringSlice{"test-api = 1.2"}))
+	require.NoError(t, p.SetReplaces(StringSlice{"test-legacy"}))
+	require.NoError(t, p.Validate())
+	target, err := p.Do(false)
+	require.NoError(t, err)
+	return target
+}
+
+func TestPackage_AddFilesApk(t *testing.T) {
+	dir := t.TempDir()
+	apk := buildTestApk(t, dir)
+
+	t.Run("deb", func(t *testing.T) {
+		p := Packager{InputType: INPUT_APK, OutputType: DEB, OutDir: t.TempDir()}
+		require.NoError(t, p.Init())
+		defer p.Close()
+
+		require.NoError(t, p.AddFiles(StringSlice{apk}))
+
+		assert.Equal(t, "test", p.Info.Name)
+		assert.Equal(t, "1.2.3", p.Info.Version)
+		assert.Equal(t, "4", p.Info.Release)
+		assert.Equal(t, "arm64", p.Info.Arch)
+		assert.Equal(t, "Test <test@example.com>", p.Info.Maintainer)
+		assert.Equal(t, "test package\nlong description", p.Info.Description)
+		assert.Equal(t, "https://example.com", p.Info.Homepage)
+		assert.Equal(t, "MIT", p.Info.License)
+		assert.Equal(t, []string{"musl (>= 1.2)"}, p.Info.Depends)
+		assert.Equal(t, []string{"test-api (= 1.2)"}, p.Info.Provides)
+		assert.Equal(t, []string{"test-legacy"}, p.Info.Replaces)
+
+		want := map[string]string{
+			"/etc/test/test.conf": defaultStr,
+			"/usr/bin/test":       defaultStr,
+			"/usr/bin/test-link":  symlinkStr,
+		}
+		for dest, typ := range want {
+			c, ok := p.FilesMap[dest]
+			if assert.Truef(t, ok, "%s not found", dest) {
+				assert.Equal(t, typ, c.Type, dest)
+			}
+		}
+		for dest := range p.FilesMap {
+			assert.NotContains(t, dest, ".PKGINFO")
+		}
+
+		// apk has no conffiles, /etc files are marked as config only with explicit rule
+		require.NoError(t, p.SetTypeRules(nil, false, false))
+		assert.Equal(t, defaultStr, p.FilesMap["/etc/test/test.conf"].Type)
+		require.NoError(t, p.SetTypeRules(StringSlice{"/etc/**=config|noreplace"}, false, false))
+		assert.Equal(t, configStr, p.FilesMap["/etc/test/test.conf"].Type)
+		data, err := os.ReadFile(p.FilesMap["/etc/test/test.conf"].Source)
+		require.NoError(t, err)
+		assert.Equal(t, "key = value\n", string(data))
+
+		data, err = os.ReadFile(p.Info.Scripts.PostInstall)
+		require.NoError(t, err)
+		assert.Equal(t, "#!/bin/sh\necho installed\n", string(data))
+
+		assert.Equal(t, []string{
+			"apk field depend = so:libc.musl-aarch64.so.1 is not supported for deb",
+			"apk script .post-upgrade is not supported for deb",
+			"apk scripts are imported as is, check them for apk specific environment",
+		}, p.Untranslated)
+
+		require.NoError(t, p.Validate())
+		_, err = p.Do(false)
+		require.NoError(t, err)
+	})
+
+	t.Run("apk", func(t *testing.T) {
+		p := Packager{InputType: INPUT_APK, OutputType: APK, Dir: dir}
+		p.Info.Version = "2.0.0"
+		require.NoError(t, p.Init())
+		defer p.Close()
+
+		require.NoError(t, p.AddFiles(StringSlice{path.Base(apk) + "/usr/bin=/opt/test/bin"}))
+
+		assert.Equal(t, "2.0.0", p.Info.Version)
+		assert.Equal(t, "", p.Info.Release)
+		assert.Equal(t, []string{"musl>=1.2", "so:libc.musl-aarch64.so.1"}, p.Info.Depends)
+		assert.Empty(t, p.Untranslated)
+
+		data, err := os.ReadFile(p.Info.APK.Scripts.PostUpgrade)
+		require.NoError(t, err)
+		assert.Equal(t, "#!/bin/sh\necho upgraded\n", string(data))
+
+		assert.Len(t, p.FilesMap, 2)
+		assert.Contains(t, p.FilesMap, "/opt/test/bin/test")
+		assert.Contains(t, p.FilesMap, "/opt/test/bin/test-link")
+	})
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goreleaser/nfpm/v2/files"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// archiveEntry is a file from archive input
+type archiveEntry struct {
+	// Name is a path inside archive
+	Name string
+	// Type is a content type (defaultStr, dirStr, symlinkStr or file type from package)
+	Type string
+	// Target is a symlink target
+	Target   string
+	FileInfo files.ContentFileInfo
+}
+
+// archiveImporter add archive entries as package contents, file data is staged in temporary dir
+type archiveImporter struct {
+	p  *Packager
+	fm FileMap
+	// prefix is a source path inside archive (may be a glob)
+	prefix string
+	glob   bool
+	// staged is a staged files by archive path (for hardlinks)
+	staged map[string]string
+	// targets is a known hardlink targets (all regular files may be targets, if not set)
+	targets map[string]bool
+	// excluded is a excluded dirs (entries inside them are skipped too)
+	excluded []string
+	// dirs is a selected dirs, only empty dirs are added after archive is read (like for dir input)
+	dirs []archiveDir
+	// parents is a archive paths of non-empty dirs
+	parents map[string]bool
+}
+
+// archiveDir is a selected dir entry with package path
+type archiveDir struct {
+	e    *archiveEntry
+	dest string
+}
+
+func newArchiveImporter(p *Packager, fm FileMap, prefix string) *archiveImporter {
+	a := &archiveImporter{p: p, fm: fm, staged: make(map[string]string), parents: make(map[string]bool)}
+	if lit, ok := globLiteral(prefix); ok {
+		a.prefix = cleanEntryName(lit)
+	} else {
+		a.prefix = cleanEntryName(prefix)
+		a.glob = true
+	}
+	return a
+}
+
+// addArchives add entries from archives matched by mapping source, read is called for every archive
+func (p *Packager) addArchives(fm FileMap, read func(name string, a *archiveImporter) error) error {
+	sources, prefix, err := p.archiveSources(fm)
+	if err != nil {
+		return err
+	}
+	for _, src := range sources {
+		a := newArchiveImporter(p, fm, prefix)
+		if err = read(src, a); err == nil {
+			err = a.addDirs()
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+	}
+	return nil
+}
+
+// archiveSources split mapping source ARCHIVE[/PREFIX] (relative to Packager.Dir) to archive paths and path prefix inside archive.
+// Archive path may be a glob if prefix is not set.
+func (p *Packager) archiveSources(fm FileMap) ([]string, string, error) {
+	src := fm.Src
+	if len(p.Dir) > 0 && !path.IsAbs(src) {
+		src = path.Join(globEscape(p.Dir), src)
+	}
+	elems := strings.Split(src, "/")
+	for i := 1; i <= len(elems); i++ {
+		name, ok := globLiteral(strings.Join(elems[:i], "/"))
+		if !ok {
+			break
+		}
+		if st, err := os.Stat(name); err == nil && st.Mode().IsRegular() {
+			return []string{name}, strings.Join(elems[i:], "/"), nil
+		}
+	}
+	matches, err := filepath.Glob(src)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(matches) == 0 {
+		return nil, "", fmt.Errorf("%s: no such file or directory", fm.Src)
+	}
+	return matches, "", nil
+}
+
+// cleanEntryName normalize path inside archive (leading '/' and '..' elements are dropped), empty name is for archive root
+func cleanEntryName(name string) string {
+	return path.Clean("/" + name)[1:]
+}
+
+// relName return entry path relative to mapping source (like for dir input: source dir contents or parent dir of glob matches),
+// false if entry is not inside source prefix
+func (a *archiveImporter) relName(name string) (string, bool, error) {
+	if a.prefix == "" {
+		return name, true, nil
+	}
+	if a.glob {
+		pattern := strings.Split(a.prefix, "/")
+		elems := strings.Split(name, "/")
+		if len(elems) < len(pattern) {
+			return "", false, nil
+		}
+		ok, err := matchElems(pattern, elems[:len(pattern)])
+		if !ok || err != nil {
+			return "", false, err
+		}
+		return strings.Join(elems[len(pattern)-1:], "/"), true, nil
+	}
+	if name == a.prefix {
+		return "", true, nil
+	}
+	if strings.HasPrefix(name, a.prefix+"/") {
+		return name[len(a.prefix)+1:], true, nil
+	}
+	return "", false, nil
+}
+
+// destination return package path for entry, without mapping destination archive path is placed under Packager.Prefix
+func (a *archiveImporter) destination(name, rel string) string {
+	if a.fm.Dst == "" {
+		return path.Join("/", a.p.Prefix, name)
+	}
+	return path.Join(a.fm.Dst, rel)
+}
+
+// skip check entry with Packager.Exclude patterns (matched with archive path and destination)
+func (a *archiveImporter) skip(name, dest string, isDir bool) (bool, error) {
+	for _, dir := range a.excluded {
+		if strings.HasPrefix(name, dir+"/") {
+			return true, nil
+		}
+	}
+	for _, pattern := range a.p.Exclude {
+		ok, err := matchPath(pattern, name)
+		if err == nil && !ok {
+			ok, err = matchPath(pattern, dest)
+		}
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			if isDir {
+				a.excluded = append(a.excluded, name)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fileInfo merge entry attributes with default owner and group and attributes from mapping
+func (a *archiveImporter) fileInfo(e *archiveEntry) *files.ContentFileInfo {
+	fi := e.FileInfo
+	owner, group := a.p.owner()
+	if owner != "" {
+		fi.Owner = owner
+	}
+	if group != "" {
+		fi.Group = group
+	}
+	if a.fm.FileInfo != nil {
+		if a.fm.FileInfo.Mode != 0 && e.Type == defaultStr {
+			fi.Mode = a.fm.FileInfo.Mode
+		}
+		if a.fm.FileInfo.Owner != "" {
+			fi.Owner = a.fm.FileInfo.Owner
+		}
+		if a.fm.FileInfo.Group != "" {
+			fi.Group = a.fm.FileInfo.Group
+		}
+	}
+	return &fi
+}
+
+// stage write file data to temporary dir
+func (a *archiveImporter) stage(e *archiveEntry, r io.Reader) (string, error) {
+	name, err := a.p.stage(r, e.FileInfo.MTime)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", e.Name, err)
+	}
+	return name, nil
+}
+
+// selected return package path for entry, false if entry is not inside mapping source or excluded
+func (a *archiveImporter) selected(e *archiveEntry) (string, bool, error) {
+	rel, ok, err := a.relName(e.Name)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	if e.Type == dirStr && rel == "" {
+		// source root is not owned, like for dir input
+		return "", false, nil
+	}
+	dest := a.destination(e.Name, rel)
+	if skip, err := a.skip(e.Name, dest, e.Type == dirStr); skip || err != nil {
+		return "", false, err
+	}
+	return dest, true, nil
+}
+
+// markParents mark parent dirs of archive path as non-empty
+func (a *archiveImporter) markParents(name string) {
+	for dir := path.Dir(name); dir != "." && dir != "/" && !a.parents[dir]; dir = path.Dir(dir) {
+		a.parents[dir] = true
+	}
+}
+
+// add append archive entry, r is a file data (for regular files)
+func (a *archiveImporter) add(e *archiveEntry, r io.Reader) error {
+	e.Name = cleanEntryName(e.Name)
+	a.markParents(e.Name)
+	dest, ok, err := a.selected(e)
+	if !ok || err != nil {
+		return err
+	}
+
+	var source string
+	switch e.Type {
+	case symlinkStr:
+		source = e.Target
+	case dirStr:
+		// parent dirs are not owned, emptiness is known after archive is read
+		a.dirs = append(a.dirs, archiveDir{e: e, dest: dest})
+		return nil
+	case ghostStr:
+	default:
+		if source, err = a.stage(e, r); err != nil {
+			return err
+		}
+		a.staged[e.Name] = source
+	}
+	return a.addContent(e, dest, source)
+}
+
+// addTarget append regular file, which may be a hardlink target:
+// file data is staged even if entry is not selected (only for known targets, if targets are set)
+func (a *archiveImporter) addTarget(e *archiveEntry, r io.Reader) error {
+	e.Name = cleanEntryName(e.Name)
+	a.markParents(e.Name)
+	dest, ok, err := a.selected(e)
+	if err != nil {
+		return err
+	}
+	if !ok && a.targets != nil && !a.targets[e.Name] {
+		return nil
+	}
+	staged, err := a.stage(e, r)
+	if err != nil {
+		return err
+	}
+	a.staged[e.Name] = staged
+	if !ok {
+		return nil
+	}
+	return a.addContent(e, dest, staged)
+}
+
+// addContent append package content for selected entry
+func (a *archiveImporter) addContent(e *archiveEntry, dest, source string) error {
+	c := &files.Content{Source: source, Destination: dest, Type: e.Type, FileInfo: a.fileInfo(e)}
+	if _, ok := a.p.FilesMap[c.Destination]; ok {
+		return fmt.Errorf("filemap produce duplicate: %s", c.Destination)
+	}
+	a.p.Info.Contents = append(a.p.Info.Contents, c)
+	a.p.FilesMap[c.Destination] = c
+	return nil
+}
+
+// addDirs append selected empty dirs, must be called after all archive entries are added
+func (a *archiveImporter) addDirs() error {
+	for _, d := range a.dirs {
+		if a.parents[d.e.Name] {
+			continue
+		}
+		if err := a.addContent(d.e, d.dest, ""); err != nil {
+			return err
+		}
+	}
+	a.dirs = nil
+	return nil
+}
+
+// addLink append hardlink as a copy of already added file
+func (a *archiveImporter) addLink(e *archiveEntry) error {
+	staged, ok := a.staged[cleanEntryName(e.Target)]
+	if !ok {
+		return fmt.Errorf("%s: hardlink target not found: %s", e.Name, e.Target)
+	}
+	f, err := os.Open(staged)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return a.add(e, f)
+}
+
+// decompress detect compression (gzip, bzip2, xz or zstd) by magic, uncompressed stream is returned as is
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(6)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return io.NopCloser(bzip2.NewReader(br)), nil
+	case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		xr, err := xz.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// ownerName return user or group name, numeric id is used if name is not set (0 is root)
+func ownerName(name string, id int) string {
+	if name != "" || id < 0 {
+		return name
+	}
+	if id == 0 {
+		return "root"
+	}
+	return strconv.Itoa(id)
+}
+
+// stage write data to file in temporary dir, mtime is set if not zero
+func (p *Packager) stage(r io.Reader, mtime time.Time) (string, error) {
+	dir, err := p.tempDir()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, "file")
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err = f.Close(); err != nil {
+		return "", err
+	}
+	if !mtime.IsZero() {
+		if err = os.Chtimes(f.Name(), mtime, mtime); err != nil {
+			return "", err
+		}
+	}
+	return f.Name(), nil
+}
+
+// tempDir return temporary dir for staged files, created on first use
+func (p *Packager) tempDir() (string, error) {
+	if p.stageDir == "" {
+		dir, err := os.MkdirTemp("", "nfpmc")
+		if err != nil {
+			return "", err
+		}
+		p.stageDir = dir
+	}
+	return p.stageDir, nil
+}
+
+// Close remove temporary files
+func (p *Packager) Close() error {
+	if p.stageDir == "" {
+		return nil
+	}
+	err := os.RemoveAll(p.stageDir)
+	p.stageDir = ""
+	return err
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/goreleaser/nfpm/v2/files"
+)
+
+func parseMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 07777 {
+		return 0, fmt.Errorf("mode is invalid: %s", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// parseFileInfo parse file attributes from mapping MODE[:OWNER[:GROUP]] fields, empty fields are not set
+func parseFileInfo(attrs []string) (*files.ContentFileInfo, error) {
+	if len(attrs) == 0 || len(attrs) > 3 {
+		return nil, fmt.Errorf("file attributes is invalid: %s", strings.Join(attrs, ":"))
+	}
+	fi := &files.ContentFileInfo{}
+	if len(attrs[0]) > 0 {
+		mode, err := parseMode(attrs[0])
+		if err != nil {
+			return nil, err
+		}
+		fi.Mode = mode
+	}
+	if len(attrs) > 1 {
+		fi.Owner = attrs[1]
+	}
+	if len(attrs) > 2 {
+		fi.Group = attrs[2]
+	}
+	return fi, nil
+}
+
+// owner return default files owner and group for output type
+func (p *Packager) owner() (string, string) {
+	switch p.OutputType {
+	case RPM:
+		return p.RPMUser, p.RPMGroup
+	case DEB:
+		return p.DebUser, p.DebGroup
+	default:
+		return "", ""
+	}
+}
+
+// fileInfo merge file attributes from mapping with default owner and group, return nil if nothing is set
+func (p *Packager) fileInfo(fi *files.ContentFileInfo) *files.ContentFileInfo {
+	owner, group := p.owner()
+	if fi == nil && owner == "" && group == "" {
+		return nil
+	}
+	c := &files.ContentFileInfo{Owner: owner, Group: group}
+	if fi != nil {
+		c.Mode = fi.Mode
+		if fi.Owner != "" {
+			c.Owner = fi.Owner
+		}
+		if fi.Group != "" {
+			c.Group = fi.Group
+		}
+	}
+	return c
+}
+
+// SetRPMAttrs set file attributes in format MODE,USER,GROUP:PATH (like %attr, '-' for leave unchanged), only for rpm
+func (p *Packager) SetRPMAttrs(attrs StringSlice) error {
+	for _, a := range attrs {
+		n := strings.IndexByte(a, ':')
+		if n == -1 {
+			return fmt.Errorf("rpm attr is invalid: %s", a)
+		}
+		fields := strings.Split(a[:n], ",")
+		if len(fields) != 3 {
+			return fmt.Errorf("rpm attr is invalid: %s", a)
+		}
+		var fi files.ContentFileInfo
+		if fields[0] != "-" {
+			mode, err := parseMode(fields[0])
+			if err != nil {
+				return err
+			}
+			fi.Mode = mode
+		}
+		if fields[1] != "-" {
+			fi.Owner = fields[1]
+		}
+		if fields[2] != "-" {
+			fi.Group = fields[2]
+		}
+		if p.OutputType != RPM {
+			continue
+		}
+
+		f := a[n+1:]
+		matched := p.matchFiles(f)
+		if len(matched) == 0 {
+			return fmt.Errorf("rpm attr path not found: %s", a)
+		}
+		for _, v := range matched {
+			if v.FileInfo == nil {
+				v.FileInfo = &files.ContentFileInfo{}
+			}
+			if fi.Mode != 0 {
+				v.FileInfo.Mode = fi.Mode
+			}
+			if fi.Owner != "" {
+				v.FileInfo.Owner = fi.Owner
+			}
+			if fi.Group != "" {
+				v.FileInfo.Group = fi.Group
+			}
+		}
+	}
+	return nil
+}
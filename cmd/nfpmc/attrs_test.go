@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path"
+	"runtime"
+	"testing"
+
+	"github.com/goreleaser/nfpm/v2/files"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackage_FileInfo(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	testDir := path.Join(path.Dir(path.Dir(path.Dir(filename))), "test")
+
+	err := os.Chdir(testDir)
+	assert.NoErrorf(t, err, "Chdir")
+
+	p := Packager{OutputType: RPM, RPMUser: "test", DebUser: "nobody"}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+
+	err = p.Init()
+	assert.NoErrorf(t, err, "Package.Init")
+
+	err = p.AddFiles([]string{"out/test-example=/usr/bin/test-example:0750:svc:svc", "conf/=/etc/test/:0640::test", "docs/=/usr/share/test/"})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+
+	err = p.SetRPMAttrs([]string{"600,-,-:/etc/test", "-,root,-:/usr/bin/test-example"})
+	assert.NoErrorf(t, err, "Package.SetRPMAttrs")
+
+	verifyContent := files.Contents{
+		&files.Content{
+			Source: "out/test-example", Destination: "/usr/bin/test-example", Type: defaultStr,
+			FileInfo: &files.ContentFileInfo{Owner: "root", Group: "svc", Mode: 0750},
+		},
+		&files.Content{
+			Source: "conf/test-example.conf", Destination: "/etc/test/test-example.conf", Type: defaultStr,
+			FileInfo: &files.ContentFileInfo{Owner: "test", Group: "test", Mode: 0600},
+		},
+		&files.Content{
+			Source: "docs/test-example.txt", Destination: "/usr/share/test/test-example.txt", Type: defaultStr,
+			FileInfo: &files.ContentFileInfo{Owner: "test"},
+		},
+	}
+	assert.Equalf(t, verifyContent, p.Info.Contents, "Package.AddFiles Contents mismatch")
+
+	err = p.AddSymlinks([]string{"test-example=/usr/bin/test-link"})
+	assert.NoErrorf(t, err, "Package.AddSymlinks")
+	assert.Equal(t, &files.ContentFileInfo{Owner: "test"}, p.FilesMap["/usr/bin/test-link"].FileInfo, "symlink owner")
+
+	for _, m := range []string{"out/test-example=/usr/bin/test:0987", "out/test-example=/usr/bin/test:0755:a:b:c"} {
+		err = p.AddFiles([]string{m})
+		if err == nil {
+			t.Errorf("Package.AddFiles(%s) success, but attributes is invalid\n", m)
+		}
+	}
+	for _, a := range []string{"600,-:/etc/test", "600,-,-", "600,-,-:/etc/missing"} {
+		err = p.SetRPMAttrs([]string{a})
+		if err == nil {
+			t.Errorf("Package.SetRPMAttrs(%s) success, but attributes is invalid\n", a)
+		}
+	}
+}
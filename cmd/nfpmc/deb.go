@@ -0,0 +1,449 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blakesmith/ar"
+	"github.com/goreleaser/nfpm/v2"
+)
+
+// debArchToGo map Debian architectures to nfpm (Go) architecture names, other names are the same
+var debArchToGo = map[string]string{
+	"i386":     "386",
+	"armel":    "arm5",
+	"armhf":    "arm7",
+	"ppc64el":  "ppc64le",
+	"mips64el": "mips64le",
+	"mipsel":   "mipsle",
+}
+
+// debControl is a deb control file fields in file order
+type debControl struct {
+	fields map[string]string
+	order  []string
+}
+
+// parseDebControl parse deb control file, continuation lines are joined with '\n' (leading space is kept)
+func parseDebControl(r io.Reader) (*debControl, error) {
+	ctrl := &debControl{fields: make(map[string]string)}
+	var field string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if field == "" {
+				return nil, fmt.Errorf("control:%d: unexpected continuation line", n)
+			}
+			ctrl.fields[field] += "\n" + line
+			continue
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 1 {
+			return nil, fmt.Errorf("control:%d: field is invalid: %s", n, line)
+		}
+		field = line[:i]
+		if _, ok := ctrl.fields[field]; !ok {
+			ctrl.order = append(ctrl.order, field)
+		}
+		ctrl.fields[field] = strings.TrimSpace(line[i+1:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ctrl, nil
+}
+
+// splitDebVersion split deb version [EPOCH:]UPSTREAM[-REVISION]
+func splitDebVersion(s string) (epoch, version, release string) {
+	if n := strings.IndexByte(s, ':'); n != -1 {
+		epoch, s = s[:n], s[n+1:]
+	}
+	if n := strings.LastIndexByte(s, '-'); n != -1 {
+		s, release = s[:n], s[n+1:]
+	}
+	return epoch, s, release
+}
+
+// debDescription convert deb description (synopsis and extended lines with leading space, " ." for empty line) to text
+func debDescription(s string) string {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimPrefix(lines[i], " ")
+		if line == "." {
+			line = ""
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stripDebRelation remove architecture qualifier, architecture restrictions and build profiles from deb relation
+func stripDebRelation(rel string) string {
+	if n := strings.IndexByte(rel, '['); n != -1 {
+		rel = rel[:n]
+	}
+	if n := strings.Index(rel, " <"); n != -1 && !strings.HasPrefix(rel[n+2:], "<") && !strings.HasPrefix(rel[n+2:], "=") {
+		rel = rel[:n]
+	}
+	rel = strings.TrimSpace(rel)
+	if n := strings.IndexAny(rel, " ("); n != -1 {
+		if i := strings.IndexByte(rel[:n], ':'); i != -1 {
+			rel = rel[:i] + rel[n:]
+		}
+	} else if i := strings.IndexByte(rel, ':'); i != -1 {
+		rel = rel[:i]
+	}
+	return rel
+}
+
+// untranslated append message to untranslated fields report
+func (p *Packager) untranslated(format string, a ...interface{}) {
+	p.Untranslated = append(p.Untranslated, fmt.Sprintf(format, a...))
+}
+
+// importDebRelations translate deb relations field to output package syntax
+func (p *Packager) importDebRelations(field, value string) ([]string, error) {
+	var rels []string
+	for _, rel := range strings.Split(value, ",") {
+		rel = strings.Join(strings.Fields(rel), " ")
+		if rel == "" {
+			continue
+		}
+		if p.OutputType == DEB {
+			rels = append(rels, rel)
+			continue
+		}
+		alts := strings.Split(rel, "|")
+		s, err := formatRelation(stripDebRelation(alts[0]), p.OutputType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		if len(alts) > 1 {
+			p.untranslated("deb field %s: %s is translated as %s (alternatives are supported only for deb)", field, rel, s)
+		}
+		rels = append(rels, s)
+	}
+	return rels, nil
+}
+
+func setIfEmpty(s *string, value string) {
+	if *s == "" {
+		*s = value
+	}
+}
+
+// importDebFields import deb control fields to package info, fields from command line are not overwritten
+func (p *Packager) importDebFields(ctrl *debControl) error {
+	output := p.OutputType.String()
+	for _, field := range ctrl.order {
+		value := ctrl.fields[field]
+		var (
+			rels []string
+			err  error
+		)
+		switch field {
+		case "Depends", "Pre-Depends", "Recommends", "Suggests", "Conflicts", "Breaks", "Replaces", "Provides", "Enhances":
+			if rels, err = p.importDebRelations(field, value); err != nil {
+				return err
+			}
+		}
+
+		switch field {
+		case "Package":
+			setIfEmpty(&p.Info.Name, value)
+		case "Version":
+			if p.Info.Version == "" {
+				epoch, version, release := splitDebVersion(value)
+				if p.OutputType == RPM && strings.Contains(version, "-") {
+					p.untranslated("deb field Version: %s is translated as %s (rpm version can't contain '-')", version, strings.ReplaceAll(version, "-", "_"))
+					version = strings.ReplaceAll(version, "-", "_")
+				}
+				p.Info.Version = version
+				setIfEmpty(&p.Info.Epoch, epoch)
+				setIfEmpty(&p.Info.Release, release)
+			}
+		case "Architecture":
+			if arch, ok := debArchToGo[value]; ok {
+				value = arch
+			}
+			setIfEmpty(&p.Info.Arch, value)
+		case "Maintainer":
+			setIfEmpty(&p.Info.Maintainer, value)
+		case "Description":
+			setIfEmpty(&p.Info.Description, debDescription(value))
+		case "Homepage":
+			setIfEmpty(&p.Info.Homepage, value)
+		case "Installed-Size":
+			// calculated by packager
+		case "Depends":
+			p.Info.Depends = append(p.Info.Depends, rels...)
+		case "Conflicts":
+			p.Info.Conflicts = append(p.Info.Conflicts, rels...)
+		case "Replaces":
+			p.Info.Replaces = append(p.Info.Replaces, rels...)
+		case "Provides":
+			p.Info.Provides = append(p.Info.Provides, rels...)
+		case "Recommends", "Suggests":
+			if p.OutputType == APK {
+				p.untranslated("deb field %s is not supported for %s", field, output)
+			} else if field == "Recommends" {
+				p.Info.Recommends = append(p.Info.Recommends, rels...)
+			} else {
+				p.Info.Suggests = append(p.Info.Suggests, rels...)
+			}
+		case "Pre-Depends":
+			if p.OutputType == DEB {
+				p.Info.Deb.Predepends = append(p.Info.Deb.Predepends, rels...)
+			} else {
+				p.untranslated("deb field Pre-Depends is translated as Depends")
+				p.Info.Depends = append(p.Info.Depends, rels...)
+			}
+		case "Breaks":
+			if p.OutputType == DEB {
+				p.Info.Deb.Breaks = append(p.Info.Deb.Breaks, rels...)
+			} else {
+				p.untranslated("deb field Breaks is translated as Conflicts")
+				p.Info.Conflicts = append(p.Info.Conflicts, rels...)
+			}
+		case "Section":
+			if p.OutputType == DEB {
+				setIfEmpty(&p.Info.Section, value)
+			} else {
+				p.untranslated("deb field %s is not supported for %s", field, output)
+			}
+		case "Priority":
+			if p.OutputType == DEB {
+				setIfEmpty(&p.Info.Priority, value)
+			} else {
+				p.untranslated("deb field %s is not supported for %s", field, output)
+			}
+		default:
+			if p.OutputType != DEB {
+				p.untranslated("deb field %s is not supported for %s", field, output)
+				break
+			}
+			if p.Info.Deb.Fields == nil {
+				p.Info.Deb.Fields = make(map[string]string)
+			}
+			if field == "Enhances" {
+				value = strings.Join(rels, ", ")
+			}
+			if _, ok := p.Info.Deb.Fields[field]; !ok {
+				p.Info.Deb.Fields[field] = value
+			}
+		}
+	}
+	return nil
+}
+
+// parseDebTriggers parse deb triggers control file
+func parseDebTriggers(data []byte, t *nfpm.DebTriggers) error {
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("triggers:%d: trigger is invalid: %s", n+1, line)
+		}
+		switch fields[0] {
+		case "interest":
+			t.Interest = append(t.Interest, fields[1])
+		case "interest-await":
+			t.InterestAwait = append(t.InterestAwait, fields[1])
+		case "interest-noawait":
+			t.InterestNoAwait = append(t.InterestNoAwait, fields[1])
+		case "activate":
+			t.Activate = append(t.Activate, fields[1])
+		case "activate-await":
+			t.ActivateAwait = append(t.ActivateAwait, fields[1])
+		case "activate-noawait":
+			t.ActivateNoAwait = append(t.ActivateNoAwait, fields[1])
+		default:
+			return fmt.Errorf("triggers:%d: trigger is invalid: %s", n+1, line)
+		}
+	}
+	return nil
+}
+
+// importDebControl import metadata, conffiles and maintainer scripts from deb control archive files
+func (p *Packager) importDebControl(control map[string][]byte, a *archiveImporter) error {
+	data, ok := control["control"]
+	if !ok {
+		return fmt.Errorf("control file not found")
+	}
+	ctrl, err := parseDebControl(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if err = p.importDebFields(ctrl); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(control))
+	for name := range control {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	scripts := map[string]*string{
+		"preinst":   &p.Info.Scripts.PreInstall,
+		"postinst":  &p.Info.Scripts.PostInstall,
+		"prerm":     &p.Info.Scripts.PreRemove,
+		"postrm":    &p.Info.Scripts.PostRemove,
+		"config":    &p.Info.Deb.Scripts.Config,
+		"templates": &p.Info.Deb.Scripts.Templates,
+	}
+	var maintScripts bool
+	for _, name := range names {
+		data := control[name]
+		switch name {
+		case "control", "md5sums":
+			// md5sums are calculated by packager
+		case "conffiles":
+			for _, line := range strings.Split(string(data), "\n") {
+				line = strings.TrimSpace(line)
+				if line == "" {
+					continue
+				}
+				if strings.HasPrefix(line, "remove-on-upgrade ") {
+					p.untranslated("deb conffile %s is not supported", line)
+					continue
+				}
+				name := cleanEntryName(line)
+				rel, ok, err := a.relName(name)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+				if c, ok := p.FilesMap[a.destination(name, rel)]; ok && c.Type == defaultStr {
+					c.Type = configStr
+				}
+			}
+		case "preinst", "postinst", "prerm", "postrm", "config", "templates":
+			if (name == "config" || name == "templates") && p.OutputType != DEB {
+				p.untranslated("deb control file %s is not supported for %s", name, p.OutputType.String())
+				continue
+			}
+			if *scripts[name] != "" {
+				// set from command line
+				continue
+			}
+			if *scripts[name], err = p.stage(bytes.NewReader(data), time.Time{}); err != nil {
+				return err
+			}
+			if name != "config" && name != "templates" {
+				maintScripts = true
+			}
+		case "triggers":
+			if p.OutputType != DEB {
+				p.untranslated("deb control file %s is not supported for %s", name, p.OutputType.String())
+				continue
+			}
+			if err = parseDebTriggers(data, &p.Info.Deb.Triggers); err != nil {
+				return err
+			}
+		default:
+			p.untranslated("deb control file %s is not supported", name)
+		}
+	}
+	if maintScripts && p.OutputType != DEB {
+		p.untranslated("deb maintainer scripts are imported as is, check them for deb specific arguments (configure, upgrade, failed-upgrade, purge)")
+	}
+	return nil
+}
+
+// readDebControlTar read files from deb control archive
+func readDebControlTar(r io.Reader) (map[string][]byte, error) {
+	dr, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	defer dr.Close()
+
+	control := make(map[string][]byte)
+	tr := tar.NewReader(dr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return control, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		control[cleanEntryName(hdr.Name)] = data
+	}
+}
+
+// readDebData add files from deb data archive
+func readDebData(r io.Reader, a *archiveImporter) error {
+	dr, err := decompress(r)
+	if err != nil {
+		return err
+	}
+	defer dr.Close()
+	return readTarEntries(tar.NewReader(dr), a)
+}
+
+// addDeb add files and metadata from deb package
+func (p *Packager) addDeb(fm FileMap) error {
+	return p.addArchives(fm, p.readDeb)
+}
+
+func (p *Packager) readDeb(name string, a *archiveImporter) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var control map[string][]byte
+	ar := ar.NewReader(f)
+	for {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		member := strings.TrimSuffix(hdr.Name, "/")
+		switch {
+		case member == "debian-binary":
+		case strings.HasPrefix(member, "control.tar"):
+			control, err = readDebControlTar(ar)
+		case strings.HasPrefix(member, "data.tar"):
+			err = readDebData(ar, a)
+		default:
+			p.untranslated("deb member %s is not supported", member)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", member, err)
+		}
+	}
+	if control == nil {
+		return fmt.Errorf("control archive not found")
+	}
+	return p.importDebControl(control, a)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseDebControl(t *testing.T) {
+	ctrl, err := parseDebControl(strings.NewReader("Package: test\nVersion: 1:1.0-2\nDepends: a,\n b (>= 1)\nDescription: synopsis\n line one\n .\n line two\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Package", "Version", "Depends", "Description"}, ctrl.order)
+	assert.Equal(t, "a,\n b (>= 1)", ctrl.fields["Depends"])
+	assert.Equal(t, "synopsis\nline one\n\nline two", debDescription(ctrl.fields["Description"]))
+
+	_, err = parseDebControl(strings.NewReader(" continuation\n"))
+	assert.Error(t, err)
+	_, err = parseDebControl(strings.NewReader("Package\n"))
+	assert.Error(t, err)
+}
+
+func Test_splitDebVersion(t *testing.T) {
+	tests := []struct {
+		version                   string
+		epoch, upstream, revision string
+	}{
+		{version: "1.0", upstream: "1.0"},
+		{version: "1.0-2", upstream: "1.0", revision: "2"},
+		{version: "3:1.0-rc1-2ubuntu1", epoch: "3", upstream: "1.0-rc1", revision: "2ubuntu1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			epoch, upstream, revision := splitDebVersion(tt.version)
+			assert.Equal(t, tt.epoch, epoch)
+			assert.Equal(t, tt.upstream, upstream)
+			assert.Equal(t, tt.revision, revision)
+		})
+	}
+}
+
+func Test_stripDebRelation(t *testing.T) {
+	tests := []struct {
+		rel  string
+		want string
+	}{
+		{rel: "libc6", want: "libc6"},
+		{rel: "libc6 (>= 2.28)", want: "libc6 (>= 2.28)"},
+		{rel: "libc6 (<< 3)", want: "libc6 (<< 3)"},
+		{rel: "python3:any (>= 3.7)", want: "python3 (>= 3.7)"},
+		{rel: "python3:any", want: "python3"},
+		{rel: "libfoo (>= 1) [amd64 arm64]", want: "libfoo (>= 1)"},
+		{rel: "libfoo <!nocheck>", want: "libfoo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			assert.Equal(t, tt.want, stripDebRelation(tt.rel))
+		})
+	}
+}
+
+// buildTestDeb build deb package with nfpmc
+func buildTestDeb(t *testing.T, dir string) string {
+	srcDir := path.Join(dir, "src")
+	for name, data := range map[string]string{
+		"etc/test/test.conf": "key = value\n",
+		"usr/bin/test":       "#!/bin/sh\n",
+		"postinst":           "#!/bin/sh\necho configured\n",
+		"triggers":           "interest /usr/lib/test\n",
+	} {
+		name = path.Join(srcDir, name)
+		require.NoError(t, os.MkdirAll(path.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(data), 0755))
+	}
+
+	p := Packager{OutputType: DEB, OutDir: dir, Dir: srcDir}
+	p.Info.Name = "test"
+	p.Info.Version = "1.2.3"
+	p.Info.Release = "4"
+	p.Info.Epoch = "1"
+	p.Info.Arch = "i386"
+	p.Info.Maintainer = "Test <test@example.com>"
+	p.Info.Description = "test package\nlong description"
+	p.Info.Homepage = "https://example.com"
+	p.Info.Section = "utils"
+	p.Info.Scripts.PostInstall = path.Join(srcDir, "postinst")
+	p.Info.Deb.Triggers.Interest = []string{"/usr/lib/test"}
+
+	require.NoError(t, p.Init())
+	require.NoError(t, p.AddFiles(StringSlice{"etc/=/etc/", "usr/=/usr/"}))
+	require.NoError(t, p.SetConfigFiles(StringSlice{"/etc/test"}))
+	require.NoError(t, p.SetDepends(StringSlice{"libc6 >= 2.28", "a | b"}))
+	require.NoError(t, p.SetConflicts(StringSlice{"test-old"}))
+	require.NoError(t, p.SetDebPreDepends(StringSlice{"dpkg"}))
+	require.NoError(t, p.SetDebBreaks(StringSlice{"test-legacy < 1.0"}))
+	require.NoError(t, p.SetDebRecommends(StringSlice{"test-doc"}))
+	require.NoError(t, p.SetDebEnhances(StringSlice{"test-base"}))
+	require.NoError(t, p.Validate())
+	target, err := p.Do(false)
+	require.NoError(t, err)
+	return target
+}
+
+func TestPackage_AddFilesDeb(t *testing.T) {
+	dir := t.TempDir()
+	deb := buildTestDeb(t, dir)
+
+	t.Run("rpm", func(t *testing.T) {
+		p := Packager{InputType: INPUT_DEB, OutputType: RPM, OutDir: t.TempDir()}
+		require.NoError(t, p.Init())
+		defer p.Close()
+
+		require.NoError(t, p.AddFiles(StringSlice{deb}))
+
+		assert.Equal(t, "test", p.Info.Name)
+		assert.Equal(t, "1.2.3", p.Info.Version)
+		assert.Equal(t, "4", p.Info.Release)
+		assert.Equal(t, "1", p.Info.Epoch)
+		assert.Equal(t, "386", p.Info.Arch)
+		assert.Equal(t, "Test <test@example.com>", p.Info.Maintainer)
+		assert.Equal(t, "test package\nlong description", p.Info.Description)
+		assert.Equal(t, "https://example.com", p.Info.Homepage)
+		assert.Equal(t, []string{"dpkg", "libc6 >= 2.28", "a"}, p.Info.Depends)
+		assert.Equal(t, []string{"test-old", "test-legacy < 1.0"}, p.Info.Conflicts)
+		assert.Equal(t, []string{"test-doc"}, p.Info.Recommends)
+
+		assert.Equal(t, configStr, p.FilesMap["/etc/test/test.conf"].Type)
+		assert.Equal(t, defaultStr, p.FilesMap["/usr/bin/test"].Type)
+		// parent dirs are owned by filesystem package
+		for _, dir := range []string{"/etc", "/etc/test", "/usr", "/usr/bin"} {
+			assert.NotContains(t, p.FilesMap, dir)
+		}
+		data, err := os.ReadFile(p.Info.Scripts.PostInstall)
+		require.NoError(t, err)
+		assert.Equal(t, "#!/bin/sh\necho configured\n", string(data))
+
+		assert.Equal(t, []string{
+			"deb field Section is not supported for rpm",
+			"deb field Priority is not supported for rpm",
+			"deb field Pre-Depends is translated as Depends",
+			"deb field Depends: a | b is translated as a (alternatives are supported only for deb)",
+			"deb field Breaks is translated as Conflicts",
+			"deb field Enhances is not supported for rpm",
+			"deb control file triggers is not supported for rpm",
+			"deb maintainer scripts are imported as is, check them for deb specific arguments (configure, upgrade, failed-upgrade, purge)",
+		}, p.Untranslated)
+
+		require.NoError(t, p.Validate())
+		_, err = p.Do(false)
+		require.NoError(t, err)
+	})
+
+	t.Run("deb", func(t *testing.T) {
+		p := Packager{InputType: INPUT_DEB, OutputType: DEB, Dir: dir}
+		p.Info.Version = "2.0.0"
+		p.Info.Description = "overwritten"
+		require.NoError(t, p.Init())
+		defer p.Close()
+
+		require.NoError(t, p.AddFiles(StringSlice{path.Base(deb) + "/etc=/etc"}))
+
+		assert.Equal(t, "2.0.0", p.Info.Version)
+		assert.Equal(t, "", p.Info.Release)
+		assert.Equal(t, "overwritten", p.Info.Description)
+		assert.Equal(t, "utils", p.Info.Section)
+		assert.Equal(t, []string{"libc6 (>= 2.28)", "a | b"}, p.Info.Depends)
+		assert.Equal(t, []string{"dpkg"}, p.Info.Deb.Predepends)
+		assert.Equal(t, []string{"test-legacy (<< 1.0)"}, p.Info.Deb.Breaks)
+		assert.Equal(t, map[string]string{"Enhances": "test-base"}, p.Info.Deb.Fields)
+		assert.Equal(t, []string{"/usr/lib/test"}, p.Info.Deb.Triggers.Interest)
+		assert.Empty(t, p.Untranslated)
+
+		assert.Len(t, p.FilesMap, 1)
+		assert.Equal(t, configStr, p.FilesMap["/etc/test/test.conf"].Type)
+	})
+}
+
+func TestPackage_AddFilesDebTypes(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := path.Join(dir, "src")
+	require.NoError(t, os.MkdirAll(path.Join(srcDir, "etc/app"), 0755))
+	require.NoError(t, os.WriteFile(path.Join(srcDir, "etc/app/a.conf"), []byte("a\n"), 0644))
+
+	// package without conffiles
+	src := Packager{OutputType: DEB, OutDir: dir, Dir: srcDir}
+	src.Info.Name = "test"
+	src.Info.Version = "1.0.0"
+	src.Info.Release = "1"
+	src.Info.Description = "test package"
+	require.NoError(t, src.Init())
+	require.NoError(t, src.AddFiles(StringSlice{"etc/=/etc/"}))
+	require.NoError(t, src.Validate())
+	deb, err := src.Do(false)
+	require.NoError(t, err)
+
+	p := Packager{InputType: INPUT_DEB, OutputType: DEB, OutDir: t.TempDir()}
+	require.NoError(t, p.Init())
+	defer p.Close()
+
+	require.NoError(t, p.AddFiles(StringSlice{deb}))
+	require.NoError(t, p.SetTypeRules(nil, false, false))
+	assert.Equal(t, defaultStr, p.FilesMap["/etc/app/a.conf"].Type)
+}
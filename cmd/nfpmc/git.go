@@ -0,0 +1,101 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// gitRef return tree-ish for git input type (HEAD by default)
+func (p *Packager) gitRef() string {
+	if p.GitRef == "" {
+		return "HEAD"
+	}
+	return p.GitRef
+}
+
+// git run git command in Packager.Dir (or current dir), stdout is returned
+func (p *Packager) git(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = p.Dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// tagVersion return version from tag name (like v1.2.3 or 1.2.3)
+func tagVersion(tag string) string {
+	if len(tag) > 1 && tag[0] == 'v' && tag[1] >= '0' && tag[1] <= '9' {
+		return tag[1:]
+	}
+	return tag
+}
+
+// setGitVersion set version from tag, if ref point to tag and version is not set from command line
+func (p *Packager) setGitVersion() error {
+	if _, err := p.git("rev-parse", "--verify", "--quiet", p.gitRef()+"^{tree}"); err != nil {
+		return fmt.Errorf("ref is invalid: %s", p.gitRef())
+	}
+	if p.Info.Version != "" {
+		return nil
+	}
+	if p.GitRef != "" {
+		// commit may have several tags, use the given one
+		if _, err := p.git("rev-parse", "--verify", "--quiet", "refs/tags/"+p.GitRef); err == nil {
+			p.Info.Version = tagVersion(p.GitRef)
+			return nil
+		}
+	}
+	tag, err := p.git("describe", "--tags", "--exact-match", p.gitRef())
+	if err != nil {
+		return fmt.Errorf("version not set (ref %s is not a tag)", p.gitRef())
+	}
+	p.Info.Version = tagVersion(tag)
+	return nil
+}
+
+// addGit add files from committed tree (like git archive, export-ignore attributes are applied), source is a path (or glob) inside tree
+func (p *Packager) addGit(fm FileMap) error {
+	if path.IsAbs(fm.Src) {
+		return fmt.Errorf("%s: path inside git tree must be relative", fm.Src)
+	}
+	var stderr bytes.Buffer
+	// modes from tree entries (0644 or 0755), not from default tar.umask
+	cmd := exec.Command("git", "-c", "tar.umask=0022", "archive", "--format=tar", p.gitRef())
+	cmd.Dir = p.Dir
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	a := newArchiveImporter(p, fm, fm.Src)
+	if err = readTarEntries(tar.NewReader(stdout), a); err == nil {
+		err = a.addDirs()
+	}
+	// drain stdout, so git is not blocked on error
+	_, _ = io.Copy(io.Discard, stdout)
+	if waitErr := cmd.Wait(); waitErr != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("git archive %s: %s", p.gitRef(), msg)
+		}
+		return fmt.Errorf("git archive %s: %w", p.gitRef(), waitErr)
+	}
+	if err != nil {
+		return fmt.Errorf("git archive %s: %w", p.gitRef(), err)
+	}
+	return nil
+}
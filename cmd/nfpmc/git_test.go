@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_tagVersion(t *testing.T) {
+	for tag, want := range map[string]string{
+		"v1.2.3":     "1.2.3",
+		"1.2.3":      "1.2.3",
+		"v1.2.3-rc1": "1.2.3-rc1",
+		"version":    "version",
+	} {
+		assert.Equal(t, want, tagVersion(tag), tag)
+	}
+}
+
+// initTestGit create git repo with commit tagged v1.2.3 and aaa and dirty working copy
+func initTestGit(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	for name, data := range map[string]string{
+		"bin/app":        "#!/bin/sh\n",
+		"etc/app.conf":   "key = value\n",
+		"docs/README":    "readme\n",
+		"internal.txt":   "internal\n",
+		".gitattributes": "internal.txt export-ignore\n",
+	} {
+		name = path.Join(dir, name)
+		require.NoError(t, os.MkdirAll(path.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(data), 0644))
+	}
+	require.NoError(t, os.Chmod(path.Join(dir, "bin/app"), 0755))
+	require.NoError(t, os.Symlink("app", path.Join(dir, "bin/app-link")))
+
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	git("tag", "v1.2.3")
+	// annotated tag is preferred by git describe
+	git("tag", "-a", "-m", "other", "aaa")
+
+	// uncommitted changes are not packaged
+	require.NoError(t, os.WriteFile(path.Join(dir, "etc/app.conf"), []byte("key = dirty\n"), 0644))
+	require.NoError(t, os.WriteFile(path.Join(dir, "bin/untracked"), []byte("untracked\n"), 0755))
+	git("commit", "-q", "-a", "-m", "next")
+	require.NoError(t, os.WriteFile(path.Join(dir, "etc/app.conf"), []byte("key = dirtier\n"), 0644))
+	return dir
+}
+
+func TestPackage_AddFilesGit(t *testing.T) {
+	dir := initTestGit(t)
+
+	t.Run("tag", func(t *testing.T) {
+		p := Packager{InputType: INPUT_GIT, OutputType: DEB, Dir: dir, GitRef: "v1.2.3", OutDir: t.TempDir()}
+		p.Info.Name = "test"
+		p.Info.Release = "1"
+		require.NoError(t, p.Init())
+		defer p.Close()
+
+		assert.Equal(t, "1.2.3", p.Info.Version)
+
+		require.NoError(t, p.AddFiles(StringSlice{"bin=/usr/bin", "etc/=/etc/test/", "internal.txt=/usr/share/test/"}))
+
+		assert.Len(t, p.FilesMap, 3)
+		assert.NotContains(t, p.FilesMap, "/usr/share/test/internal.txt")
+		assert.Equal(t, os.FileMode(0755), p.FilesMap["/usr/bin/app"].FileInfo.Mode)
+		assert.Equal(t, symlinkStr, p.FilesMap["/usr/bin/app-link"].Type)
+		assert.Equal(t, "app", p.FilesMap["/usr/bin/app-link"].Source)
+		assert.NotContains(t, p.FilesMap, "/usr/bin/untracked")
+
+		c := p.FilesMap["/etc/test/app.conf"]
+		require.NotNil(t, c)
+		assert.Equal(t, os.FileMode(0644), c.FileInfo.Mode)
+		data, err := os.ReadFile(c.Source)
+		require.NoError(t, err)
+		assert.Equal(t, "key = value\n", string(data))
+
+		require.NoError(t, p.Validate())
+		_, err = p.Do(false)
+		require.NoError(t, err)
+	})
+
+	t.Run("same commit tags", func(t *testing.T) {
+		for ref, want := range map[string]string{"v1.2.3": "1.2.3", "aaa": "aaa"} {
+			p := Packager{InputType: INPUT_GIT, OutputType: DEB, Dir: dir, GitRef: ref}
+			p.Info.Name = "test"
+			p.Info.Release = "1"
+			require.NoError(t, p.Init())
+			assert.Equal(t, want, p.Info.Version, ref)
+		}
+	})
+
+	t.Run("head", func(t *testing.T) {
+		p := Packager{InputType: INPUT_GIT, OutputType: RPM, Dir: dir}
+		p.Info.Name = "test"
+		p.Info.Release = "1"
+		assert.EqualError(t, p.Init(), "version not set (ref HEAD is not a tag)")
+
+		p.Info.Version = "1.2.4"
+		require.NoError(t, p.Init())
+		defer p.Close()
+
+		require.NoError(t, p.AddFiles(StringSlice{"*/app*=/opt/test/"}))
+		assert.Contains(t, p.FilesMap, "/opt/test/app.conf")
+		assert.NotContains(t, p.FilesMap, "/opt/test/untracked")
+		data, err := os.ReadFile(p.FilesMap["/opt/test/app.conf"].Source)
+		require.NoError(t, err)
+		assert.Equal(t, "key = dirty\n", string(data))
+	})
+
+	t.Run("invalid ref", func(t *testing.T) {
+		p := Packager{InputType: INPUT_GIT, OutputType: RPM, Dir: dir, GitRef: "v9.9.9"}
+		p.Info.Name = "test"
+		p.Info.Release = "1"
+		assert.EqualError(t, p.Init(), "ref is invalid: v9.9.9")
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ignoreFileNames is a gitignore-style files, read from input dirs (later has higher precedence)
+var ignoreFileNames = []string{".gitignore", ".nfpmcignore"}
+
+// ignoreRule is a gitignore-style pattern
+type ignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreFrame is a rules, relative to base dir
+type ignoreFrame struct {
+	base  string
+	rules []ignoreRule
+}
+
+// parseIgnore parse gitignore-style patterns
+func parseIgnore(r io.Reader, name string) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// trailing spaces are ignored unless they are escaped
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+			line = line[:len(line)-1]
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var rule ignoreRule
+		if line[0] == '!' {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimLeft(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		if err := validatePattern(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: ignore pattern is invalid: %s", name, n, line)
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return rules, nil
+}
+
+// readIgnore read gitignore-style file, not existing file is not an error when optional is set
+func readIgnore(name string, optional bool) ([]ignoreRule, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if optional && os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return parseIgnore(f, name)
+}
+
+func (r *ignoreRule) match(rel string, isDir bool) (bool, error) {
+	if r.dirOnly && !isDir {
+		return false, nil
+	}
+	if !r.anchored {
+		return path.Match(r.pattern, path.Base(rel))
+	}
+	if strings.HasSuffix(r.pattern, "/**") {
+		// "dir/**" matches everything inside dir, but not dir itself
+		if ok, err := matchElems(strings.Split(r.pattern[:len(r.pattern)-3], "/"), strings.Split(rel, "/")); ok || err != nil {
+			return false, err
+		}
+	}
+	return matchElems(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// ignored check file with rules stack, last matched rule wins
+func ignored(frames []ignoreFrame, file string, isDir bool) (bool, error) {
+	var ignore bool
+	for i := range frames {
+		rel, ok := relPath(frames[i].base, file)
+		if !ok {
+			continue
+		}
+		for j := range frames[i].rules {
+			rule := &frames[i].rules[j]
+			if ok, err := rule.match(rel, isDir); err != nil {
+				return false, err
+			} else if ok {
+				ignore = !rule.negate
+			}
+		}
+	}
+	return ignore, nil
+}
+
+// relPath return file path relative to base dir, false if file is not inside base
+func relPath(base, file string) (string, bool) {
+	switch base {
+	case ".":
+		return file, !path.IsAbs(file) && file != "." && !strings.HasPrefix(file, "../")
+	case "/":
+		if !path.IsAbs(file) || file == "/" {
+			return "", false
+		}
+		return file[1:], true
+	}
+	if strings.HasPrefix(file, base+"/") {
+		return file[len(base)+1:], true
+	}
+	return "", false
+}
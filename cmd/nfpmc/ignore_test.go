@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/goreleaser/nfpm/v2/files"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ignored(t *testing.T) {
+	rules, err := parseIgnore(strings.NewReader(`
+# comment
+*.o
+!keep.o
+build/
+/dist
+doc/**/*.tmp
+cache/**
+\#notes
+trailing\ 
+`), "test")
+	assert.NoError(t, err)
+
+	nested, err := parseIgnore(strings.NewReader("!*.o\n"), "test")
+	assert.NoError(t, err)
+
+	frames := []ignoreFrame{{base: "src", rules: rules}, {base: "src/lib", rules: nested}}
+
+	tests := []struct {
+		file  string
+		isDir bool
+		want  bool
+	}{
+		{file: "src/main.o", want: true},
+		{file: "src/a/main.o", want: true},
+		{file: "src/keep.o", want: false},
+		{file: "src/lib/lib.o", want: false}, // negated in nested file
+		{file: "src/build", isDir: true, want: true},
+		{file: "src/build", isDir: false, want: false},
+		{file: "src/a/build", isDir: true, want: true},
+		{file: "src/dist", isDir: true, want: true},
+		{file: "src/a/dist", isDir: true, want: false},
+		{file: "src/doc/a.tmp", want: true},
+		{file: "src/doc/a/b/a.tmp", want: true},
+		{file: "src/cache", isDir: true, want: false},
+		{file: "src/cache/a", want: true},
+		{file: "src/#notes", want: true},
+		{file: "src/trailing ", want: true},
+		{file: "main.o", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := ignored(frames, tt.file, tt.isDir)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err = parseIgnore(strings.NewReader("*.o\n[a-\n"), "test")
+	assert.EqualError(t, err, "test:2: ignore pattern is invalid: [a-")
+}
+
+func TestPackage_AddFilesIgnore(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"src/.gitignore":      "*.o\nbuild/\n",
+		"src/.nfpmcignore":    "!main.o\n.gitignore\n.nfpmcignore\n",
+		"src/main.c":          "",
+		"src/main.o":          "",
+		"src/util.o":          "",
+		"src/build/main":      "",
+		"src/lib/lib.c":       "",
+		"src/lib/lib.o":       "",
+		"src/lib/.gitignore":  "!lib.o\n*.c\n",
+		"src/lib/.gitignore~": "",
+	} {
+		err := os.MkdirAll(path.Join(dir, path.Dir(name)), 0755)
+		assert.NoErrorf(t, err, "MkdirAll")
+		err = os.WriteFile(path.Join(dir, name), []byte(data), 0644)
+		assert.NoErrorf(t, err, "WriteFile")
+	}
+	ignoreFile := path.Join(dir, "ignore")
+	err := os.WriteFile(ignoreFile, []byte("*~\n/lib/.gitignore\n"), 0644)
+	assert.NoErrorf(t, err, "WriteFile")
+
+	p := Packager{Dir: dir, IgnoreFiles: StringSlice{ignoreFile}}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+
+	err = p.Init()
+	assert.NoErrorf(t, err, "Package.Init")
+
+	err = p.AddFiles([]string{"src/=/usr/src/test/"})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+
+	verifyContent := files.Contents{
+		&files.Content{Source: path.Join(dir, "src/lib/lib.o"), Destination: "/usr/src/test/lib/lib.o", Type: defaultStr},
+		&files.Content{Source: path.Join(dir, "src/main.c"), Destination: "/usr/src/test/main.c", Type: defaultStr},
+		&files.Content{Source: path.Join(dir, "src/main.o"), Destination: "/usr/src/test/main.o", Type: defaultStr},
+	}
+	assert.Equalf(t, verifyContent, p.Info.Contents, "Package.AddFiles Contents mismatch")
+
+	// Skip ignore files from input dirs, explicit ignore file is applied
+	p = Packager{Dir: dir, IgnoreFiles: StringSlice{ignoreFile}, NoIgnoreFiles: true}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+
+	err = p.Init()
+	assert.NoErrorf(t, err, "Package.Init")
+	err = p.AddFiles([]string{"src/=/usr/src/test/"})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+	assert.Equalf(t, 8, len(p.Info.Contents), "Package.AddFiles Contents mismatch")
+}
@@ -3,8 +3,6 @@ package main
 import (
 	"fmt"
 	"os"
-	"path"
-	"strings"
 
 	flag "github.com/spf13/pflag"
 )
@@ -25,14 +23,31 @@ import (
 
 func main() {
 	var (
-		p   Packager
-		dir string
+		p      Packager
+		inputs string
 
 		err error
 
-		configFiles  StringSlice
-		docFiles     StringSlice
-		symlinkFiles StringSlice
+		configFiles        StringSlice
+		configReplaceFiles StringSlice
+		ghostFiles         StringSlice
+		docFiles           StringSlice
+		symlinkFiles       StringSlice
+		directories        StringSlice
+		typeRules          StringSlice
+		templateValues     StringSlice
+		rpmAttrs           StringSlice
+
+		depends   StringSlice
+		provides  StringSlice
+		conflicts StringSlice
+		replaces  StringSlice
+
+		debRecommends StringSlice
+		debSuggests   StringSlice
+		debPreDepends StringSlice
+		debBreaks     StringSlice
+		debEnhances   StringSlice
 
 		noDebSystemdRestart bool
 
@@ -41,30 +56,64 @@ func main() {
 
 	flag.CommandLine.SortFlags = false
 
-	flag.VarP(&p.InputType, "input-type", "s", "the package type to use as input (dir)")
-	// flag.StringVarP(&dir, "chdir", "C", "", "(OPTIONAL) directory for searching files (not scripts)")
+	flag.VarP(&p.InputType, "input-type", "s", "the package type to use as input (dir tar zip deb rpm apk empty nfpm oci git)")
+	flag.StringVar(&p.Config, "config", "", "nfpm config file for nfpm input type (environment variables are expanded, values from command line flags override config)")
+	flag.StringVar(&p.GitRef, "ref", "", "git ref (tag, branch or commit) for git input type, version defaults to tag (default HEAD)")
+	flag.StringVarP(&p.Dir, "chdir", "C", "", "(OPTIONAL) directory for searching files (not scripts)")
+	flag.Var(&p.Exclude, "exclude", "Exclude paths matching pattern (when adding directory, matched with source and destination path, '**' matches any number of directories). This flag can be specified multiple times.")
+	flag.BoolVar(&p.FollowSymlinks, "follow-symlinks", false, "Follow symlinks found in input dirs and package the files they point to (by default symlinks are preserved)")
+	flag.Var(&p.IgnoreFiles, "ignore-file", "Exclude paths matching patterns from gitignore-style file (relative to input dirs). This flag can be specified multiple times.")
+	flag.BoolVar(&p.NoIgnoreFiles, "no-ignore-files", false, "Do not read .gitignore and .nfpmcignore files found in input dirs")
+	flag.StringVar(&p.Prefix, "prefix", "", "(OPTIONAL) A path to prefix files with when building the package (for files without explicit destination and relative symlinks)")
 
+	flag.StringVar(&inputs, "inputs", "", "(OPTIONAL) The path to a file containing a newline-separated list of files (FILE[=DEST]) and dirs to use as input, '-' for stdin (lines are literal, quotes and globs are not expanded, escape '=' and ':' with '\\')")
 	flag.VarP(&p.OutputType, "output-type", "t", "the type of package you want to create (rpm deb apk)")
 	flag.StringVar(&p.OutDir, "target", "", "(OPTIONAL) dir for store package")
 
+	flag.BoolVar(&p.Reproducible, "reproducible", false, "Build reproducible package: package and files times are set to SOURCE_DATE_EPOCH (files mtimes are clamped to it, unix epoch if not set), owners are normalized and contents are sorted")
 	flag.BoolVarP(&overwrite, "force", "f", false, "Force output even if it will overwrite an existing file")
-	flag.StringVarP(&p.OutName, "package", "p", "NAME-VERSION-ITERATION.ARCH.rpm", "The package file path to output (use NAME, VERSION, ITERATION and ARCH for substitution).")
+	flag.StringVarP(&p.OutName, "package", "p", "NAME-VERSION-ITERATION.ARCH.rpm", "The package file path to output (use NAME, EPOCH, VERSION, ITERATION and ARCH for substitution).")
 	flag.StringVarP(&p.Info.Name, "name", "n", "", "The name to give to the package")
 	flag.StringVarP(&p.Info.Version, "version", "v", "", "The version to give to the package")
 	flag.StringVarP(&p.Info.Release, "iteration", "i", "0", "The iteration to give to the package. RPM calls this the 'release'")
+	flag.StringVar(&p.Info.Epoch, "epoch", "", "The epoch value for this package. RPM and Debian calls this 'epoch'.")
 	flag.StringVarP(&p.Info.Arch, "architecture", "a", "", "The architecture name. Usually matches 'uname -m'")
 	flag.StringVarP(&p.Info.Platform, "platform", "P", "", "The platform name.")
 	flag.StringVarP(&p.Info.License, "license", "l", "", "(optional) license name for this package")
+	flag.StringVar(&p.Info.Vendor, "vendor", "", "(optional) vendor name for this package")
 	flag.StringVarP(&p.Info.Maintainer, "maintainer", "m", "", "The maintainer of this package. (default: <msv@power.test.int>")
 	flag.StringVarP(&p.Info.Description, "description", "d", "no description", "Add a description for this package")
 	flag.StringVarP(&p.Info.Homepage, "url", "u", "", "(optional) Homepage for this package")
 	flag.StringVar(&p.Info.Section, "--category", "none", "category this package belongs to")
 
+	flag.Var(&depends, "depends", "A dependency. This flag can be specified multiple times. Value is usually in the form of: --depends 'name' or --depends 'name > version'")
+	flag.Var(&provides, "provides", "What this package provides (usually a name). This flag can be specified multiple times.")
+	flag.Var(&conflicts, "conflicts", "Other packages/versions this package conflicts with. This flag can be specified multiple times.")
+	flag.Var(&replaces, "replaces", "Other packages/versions this package replaces. Equivalent of rpm's 'Obsoletes'. This flag can be specified multiple times.")
+
+	flag.Var(&debRecommends, "deb-recommends", "Add a Recommends field to the deb package (only for deb).")
+	flag.Var(&debSuggests, "deb-suggests", "Add a Suggests field to the deb package (only for deb).")
+	flag.Var(&debPreDepends, "deb-pre-depends", "Add a Pre-Depends field to the deb package (only for deb).")
+	flag.Var(&debBreaks, "deb-breaks", "Add a Breaks field to the deb package (only for deb).")
+	flag.Var(&debEnhances, "deb-enhances", "Add an Enhances field to the deb package (only for deb).")
+
 	flag.BoolVar(&noDebSystemdRestart, "no-deb-systemd-restart-after-upgrade", false, "(FAKE) fpm compability parameter, ignored")
 
 	flag.Var(&configFiles, "config-files", "Mark a file in the package as being a config file. This uses 'conffiles' in debs and %config in rpm. If you have multiple files to mark as configuration files, specify this flag multiple times. If argument is directory all files inside it will be recursively marked as config files.")
+	flag.Var(&configReplaceFiles, "config-files-replace", "Mark a file in the package as being a config file, replaced on upgrade (%config without noreplace in rpm). If argument is directory all files inside it will be recursively marked.")
+	flag.Var(&ghostFiles, "ghost-files", "Mark a file in the package as being a ghost file (%ghost in rpm, owned by package, but not shipped). Not existing files are added. If argument is directory all files inside it will be recursively marked.")
 	flag.Var(&docFiles, "doc-files", "Mark a file in the package as being a doc file.")
 	flag.Var(&symlinkFiles, "symlink-files", "Create symlink.")
+	flag.Var(&typeRules, "type-rule", "Set file type by destination pattern, e.g. --type-rule '/usr/share/doc/**=doc' (types: file config config|noreplace ghost doc license readme). Applied to files without explicit type, first matched rule wins, checked before default rules. Only these rules are applied for package and nfpm input types, e.g. --type-rule '/etc/**=config|noreplace' mark /etc files from apk as config files. This flag can be specified multiple times.")
+	flag.BoolVar(&p.NoDefaultTypeRules, "no-default-type-rules", false, "Do not apply default type rules (/etc/** is config|noreplace, /usr/share/{doc,man,info}/** is doc, /usr/share/licenses/** is license). Default type rules are never applied for package and nfpm input types")
+	flag.BoolVar(&p.DebNoDefaultConfigFiles, "deb-no-default-config-files", false, "Do not mark files under /etc as config files by default (only for deb)")
+	flag.Var(&directories, "directories", "Recursively mark a directory as being owned by the package (directory is created if not exist). This flag can be specified multiple times.")
+
+	flag.StringVar(&p.RPMUser, "rpm-user", "", "Set the user to USER in the %files section (only for rpm).")
+	flag.StringVar(&p.RPMGroup, "rpm-group", "", "Set the group to GROUP in the %files section (only for rpm).")
+	flag.Var(&rpmAttrs, "rpm-attr", "Set the attribute for a file (%attr), e.g. --rpm-attr 750,user1,group1:/some/file (only for rpm). This flag can be specified multiple times.")
+	flag.StringVar(&p.DebUser, "deb-user", "", "The owner of files in this package (only for deb).")
+	flag.StringVar(&p.DebGroup, "deb-group", "", "The group owner of files in this package (only for deb).")
 
 	flag.StringVar(&p.Info.RPM.Compression, "rpm-compression", "gzip", "Compression method. gzip works on the most platform [none|xz|xzmt|gzip|bzip2].")
 	flag.StringVar(&p.Info.Platform, "rpm-os", "linux", "Compression method. gzip works on the most platform [none|xz|xzmt|gzip|bzip2].")
@@ -80,71 +129,159 @@ func main() {
 
 	flag.StringVar(&p.PreUpgrade, "after-upgrade", "", "A script to be run after package upgrade (only for rpm, apk)")
 	flag.StringVar(&p.PostUpgrade, "before-upgrade", "", "A script to be run before package upgrade (only for rpm, apk)")
+	flag.BoolVar(&p.TemplateScripts, "template-scripts", false, "Render scripts with text/template ({{.Name}}, {{.Version}}, {{.Iteration}}, {{.Epoch}}, {{.Arch}}, {{.Platform}} and values from --template-value)")
+	flag.Var(&templateValues, "template-value", "Set a template value in format KEY=VALUE, used as {{.KEY}} in scripts and file destinations. This flag can be specified multiple times.")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Use: %s FILE1[=DEST1] [ [FILE2[=DEST2] ..]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Use: %s FILE1[=DEST1[:MODE:OWNER:GROUP]] [ [FILE2[=DEST2[:MODE:OWNER:GROUP]] ..]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Escape '=' and ':' in paths with '\\' or quote them ('..' or \"..\")\n")
+		fmt.Fprintf(os.Stderr, "With archive, package and image input types (tar zip deb rpm apk oci) FILE is ARCHIVE[/PREFIX], where PREFIX is a path (or glob) inside archive (flattened image rootfs for oci), mapped to DEST like with dir input (without DEST archive paths are placed under '/' or --prefix)\n")
+		fmt.Fprintf(os.Stderr, "With git input type FILE is a path (or glob) inside tree of --ref in repository from --chdir (or current dir)\n")
+		fmt.Fprintf(os.Stderr, "DEST is rendered with text/template (e.g. /opt/{{.Name}}-{{.Version}}/, see --template-value)\n")
 		flag.PrintDefaults()
 	}
 
 	flag.Parse()
 
+	// exit remove temporary files (extracted from archives)
+	exit := func(code int) {
+		p.Close()
+		os.Exit(code)
+	}
+
+	if p.InputType.IsPackage() || p.InputType == INPUT_NFPM {
+		// metadata is imported from package or config, so defaults are not applied
+		defaults := []string{"iteration", "description", "--category"}
+		if p.InputType == INPUT_NFPM {
+			defaults = append(defaults, "rpm-compression", "rpm-os")
+		}
+		for _, name := range defaults {
+			if f := flag.CommandLine.Lookup(name); !f.Changed {
+				f.Value.Set("")
+			}
+		}
+	}
+
 	if err = p.Init(); err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		os.Exit(1)
+		exit(1)
+	}
+	if err = p.SetTemplateValues(templateValues); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
 	}
 
-	err = p.AddFiles(flag.CommandLine.Args())
+	fileS := StringSlice(flag.CommandLine.Args())
+	if len(inputs) > 0 {
+		inputFiles, err := readInputs(inputs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			exit(1)
+		}
+		fileS = append(fileS, inputFiles...)
+	}
+
+	err = p.AddFiles(fileS)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		os.Exit(1)
+		exit(1)
 	}
 
 	err = p.AddSymlinks(symlinkFiles)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		os.Exit(1)
+		exit(1)
 	}
 
-	if len(configFiles) == 0 {
-		for _, f := range p.FilesMap {
-			if strings.HasPrefix(f.Destination, "/etc") {
-				configFiles = append(configFiles, "/etc")
-				break
-			}
-		}
+	err = p.AddDirectories(directories)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
 	}
-	err = p.SetConfigFiles(configFiles)
+
+	err = p.SetGhostFiles(ghostFiles)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		os.Exit(1)
+		exit(1)
 	}
 
-	if len(docFiles) == 0 {
-		for _, f := range p.FilesMap {
-			if strings.HasPrefix(f.Destination, "/usr/share") {
-				docFiles = append(docFiles, "/usr/share")
-				break
-			}
-		}
+	// attributes can be set for ghost files too
+	err = p.SetRPMAttrs(rpmAttrs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
+	}
+	err = p.SetConfigReplaceFiles(configReplaceFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
+	}
+
+	err = p.SetConfigFiles(configFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
 	}
 	err = p.SetDocFiles(docFiles)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		os.Exit(1)
+		exit(1)
+	}
+	err = p.SetTypeRules(typeRules, len(configFiles) > 0, len(docFiles) > 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
 	}
 
-	if p.Info.Contents.Len() == 0 {
-		fmt.Fprintf(os.Stderr, "filemap is empty\n")
-		os.Exit(1)
+	err = p.SetDepends(depends)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
+	}
+	err = p.SetProvides(provides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
+	}
+	err = p.SetConflicts(conflicts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
+	}
+	err = p.SetReplaces(replaces)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
+	}
+	err = p.SetDebRecommends(debRecommends)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
+	}
+	err = p.SetDebSuggests(debSuggests)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
+	}
+	err = p.SetDebPreDepends(debPreDepends)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
+	}
+	err = p.SetDebBreaks(debBreaks)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
+	}
+	err = p.SetDebEnhances(debEnhances)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit(1)
 	}
 
-	// append dir
-	if len(dir) > 0 || dir == "." {
-		for _, f := range p.FilesMap {
-			if f.Source[0] != '/' {
-				f.Source = path.Join(dir, f.Source)
-			}
-		}
+	if p.Info.Contents.Len() == 0 && p.InputType != INPUT_EMPTY {
+		fmt.Fprintf(os.Stderr, "filemap is empty\n")
+		exit(1)
 	}
 
 	if p.OutputType.String() == "rpm" && p.Info.Arch == "amd64" {
@@ -154,13 +291,17 @@ func main() {
 	err = p.Validate()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		os.Exit(1)
+		exit(1)
 	}
 
 	packageName, err := p.Do(overwrite)
+	p.Close()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 		os.Exit(1)
 	}
 	fmt.Printf("created package: %s\n", packageName)
+	for _, s := range p.Untranslated {
+		fmt.Fprintf(os.Stderr, "untranslated: %s\n", s)
+	}
 }
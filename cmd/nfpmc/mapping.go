@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// MappingError is a mapping parse error, Pos is a byte offset of the offending character
+type MappingError struct {
+	Mapping string
+	Pos     int
+	Msg     string
+}
+
+func (e *MappingError) Error() string {
+	return fmt.Sprintf(
+		"filemap is invalid at position %d: %s\n  %s\n  %s^",
+		e.Pos+1, e.Msg, e.Mapping, strings.Repeat(" ", utf8.RuneCountInString(e.Mapping[:e.Pos])),
+	)
+}
+
+// parseMapping parse mapping in format SRC[=DST[:MODE:OWNER:GROUP]].
+//
+// Backslash escapes the next character, text in single quotes is taken literally, in double quotes backslash escapes only '"' and '\'.
+// If srcGlob is set, SRC is a glob, so quoted text in SRC is glob-escaped and backslash before glob metacharacters is kept.
+// If attrs is false, ':' in DST is a plain character.
+func parseMapping(s string, srcGlob, attrs bool) (FileMap, error) {
+	var (
+		fm FileMap
+		sb strings.Builder
+		// field is a current field: 0 - SRC, 1 - DST, 2+ - attributes
+		field      int
+		fieldStart int
+		attrStart  int
+		attrList   []string
+	)
+
+	errorf := func(pos int, format string, a ...interface{}) (FileMap, error) {
+		return FileMap{}, &MappingError{Mapping: s, Pos: pos, Msg: fmt.Sprintf(format, a...)}
+	}
+
+	endField := func() {
+		switch field {
+		case 0:
+			fm.Src = sb.String()
+		case 1:
+			fm.Dst = sb.String()
+		default:
+			attrList = append(attrList, sb.String())
+		}
+		sb.Reset()
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 == len(s) {
+				return errorf(i, "trailing backslash")
+			}
+			i++
+			if field == 0 && srcGlob && strings.IndexByte("*?[]\\", s[i]) != -1 {
+				// keep glob escaping
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(s[i])
+		case '\'', '"':
+			end := i + 1
+			var quoted strings.Builder
+			for ; end < len(s) && s[end] != c; end++ {
+				if c == '"' && s[end] == '\\' && end+1 < len(s) && (s[end+1] == '"' || s[end+1] == '\\') {
+					end++
+				}
+				quoted.WriteByte(s[end])
+			}
+			if end == len(s) {
+				return errorf(i, "unterminated quote")
+			}
+			if field == 0 && srcGlob {
+				sb.WriteString(globEscape(quoted.String()))
+			} else {
+				sb.WriteString(quoted.String())
+			}
+			i = end
+		case '=':
+			if field > 0 {
+				return errorf(i, "unexpected '=' (escape it as '\\=')")
+			}
+			if sb.Len() == 0 {
+				return errorf(i, "source is empty")
+			}
+			endField()
+			field = 1
+			fieldStart = i + 1
+		case ':':
+			if field == 0 || (field == 1 && !attrs) {
+				sb.WriteByte(c)
+				break
+			}
+			if field == 4 {
+				return errorf(i, "unexpected ':', only MODE:OWNER:GROUP attributes supported (escape it as '\\:')")
+			}
+			endField()
+			if field == 1 {
+				attrStart = i + 1
+			}
+			field++
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	endField()
+
+	if fm.Src == "" {
+		return errorf(0, "source is empty")
+	}
+	if field > 0 && fm.Dst == "" {
+		return errorf(fieldStart, "destination is empty")
+	}
+	if len(attrList) > 0 {
+		fi, err := parseFileInfo(attrList)
+		if err != nil {
+			return errorf(attrStart, "%s", err.Error())
+		}
+		fm.FileInfo = fi
+	}
+
+	return fm, nil
+}
+
+// literalMapping convert inputs line to mapping, quotes and glob metacharacters are taken literally
+// (like in find output), only backslash escapes the next character (like '\=' or '\:')
+func literalMapping(line string) string {
+	var sb strings.Builder
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			i++
+			sb.WriteByte(c)
+		case strings.IndexByte("\\'\"*?[]", c) != -1:
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(line[i])
+	}
+	return sb.String()
+}
+
+// readInputs read mappings from file (one mapping per line, empty lines and lines started with '#' are skipped), "-" is for stdin.
+// Lines are literal (see literalMapping), so file lists from find can be used as is
+func readInputs(name string) (StringSlice, error) {
+	var r io.Reader
+	if name == "-" {
+		r = os.Stdin
+		name = "stdin"
+	} else {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var mappings StringSlice
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		mapping := literalMapping(line)
+		if _, err := parseMapping(mapping, true, true); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, n, err)
+		}
+		mappings = append(mappings, mapping)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return mappings, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/goreleaser/nfpm/v2/files"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseMapping(t *testing.T) {
+	tests := []struct {
+		mapping string
+		want    FileMap
+		wantPos int // error position, -1 for success
+	}{
+		{mapping: "out/test", want: FileMap{Src: "out/test"}, wantPos: -1},
+		{mapping: "out/test=/usr/bin/test", want: FileMap{Src: "out/test", Dst: "/usr/bin/test"}, wantPos: -1},
+		{mapping: `out/a\=b=/usr/bin/a\=b`, want: FileMap{Src: "out/a=b", Dst: "/usr/bin/a=b"}, wantPos: -1},
+		{mapping: `out/a:b=/usr/bin/a\:b`, want: FileMap{Src: "out/a:b", Dst: "/usr/bin/a:b"}, wantPos: -1},
+		{mapping: `'out/a=b*'="/usr/bin/a:b"`, want: FileMap{Src: `out/a=b\*`, Dst: "/usr/bin/a:b"}, wantPos: -1},
+		{mapping: `"out/\"a\"\\"=/usr/bin/`, want: FileMap{Src: `out/"a"\\`, Dst: "/usr/bin/"}, wantPos: -1},
+		{mapping: `out/\*=/usr/bin/`, want: FileMap{Src: `out/\*`, Dst: "/usr/bin/"}, wantPos: -1},
+		{
+			mapping: "out/*=/usr/bin/:0750:svc:svc",
+			want:    FileMap{Src: "out/*", Dst: "/usr/bin/", FileInfo: &files.ContentFileInfo{Mode: 0750, Owner: "svc", Group: "svc"}},
+			wantPos: -1,
+		},
+		{
+			mapping: "out/*=/usr/bin/::svc",
+			want:    FileMap{Src: "out/*", Dst: "/usr/bin/", FileInfo: &files.ContentFileInfo{Owner: "svc"}},
+			wantPos: -1,
+		},
+		{mapping: "", wantPos: 0},
+		{mapping: "=/usr/bin/test", wantPos: 0},
+		{mapping: "out/test=", wantPos: 9},
+		{mapping: "out/test=/usr/bin/test=test", wantPos: 22},
+		{mapping: "out/test=/usr/bin/test:0755:a:b:c", wantPos: 31},
+		{mapping: "out/test=/usr/bin/test:0999", wantPos: 23},
+		{mapping: `out/test=/usr/bin/'test`, wantPos: 18},
+		{mapping: `out/test\`, wantPos: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mapping, func(t *testing.T) {
+			got, err := parseMapping(tt.mapping, true, true)
+			if tt.wantPos == -1 {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				return
+			}
+			var mErr *MappingError
+			if assert.Truef(t, errors.As(err, &mErr), "parseMapping() error = %v, want MappingError", err) {
+				assert.Equal(t, tt.wantPos, mErr.Pos, mErr.Error())
+			}
+		})
+	}
+}
+
+func Test_parseMappingSymlink(t *testing.T) {
+	got, err := parseMapping(`../lib/a\=b:c*=/usr/bin/a:b`, false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, FileMap{Src: "../lib/a=b:c*", Dst: "/usr/bin/a:b"}, got)
+}
+
+func Test_MappingError(t *testing.T) {
+	_, err := parseMapping("out/test=/usr/bin/test=test", true, true)
+	want := "filemap is invalid at position 23: unexpected '=' (escape it as '\\=')\n" +
+		"  out/test=/usr/bin/test=test\n" +
+		"                        ^"
+	assert.EqualError(t, err, want)
+}
+
+// escapeMapping escape mapping field for parseMapping without glob and attributes
+func escapeMapping(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(`\='"`, s[i]) != -1 {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func FuzzParseMapping(f *testing.F) {
+	for _, s := range []string{
+		"out/test", "out/test=/usr/bin/test", `out/a\=b=/usr/bin/a\:b`, `'out/a=b*'="/usr/bin/a:b"`,
+		"out/*=/usr/bin/:0750:svc:svc", "out/test=/usr/bin/test=test", `out/test\`,
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, attrs := range []bool{false, true} {
+			for _, srcGlob := range []bool{false, true} {
+				fm, err := parseMapping(s, srcGlob, attrs)
+				if err != nil {
+					var mErr *MappingError
+					if !errors.As(err, &mErr) {
+						t.Fatalf("parseMapping(%q) error = %v, want MappingError", s, err)
+					}
+					if mErr.Pos < 0 || mErr.Pos > len(s) {
+						t.Fatalf("parseMapping(%q) error position %d out of range", s, mErr.Pos)
+					}
+					_ = mErr.Error()
+					continue
+				}
+				if fm.Src == "" {
+					t.Fatalf("parseMapping(%q) source is empty", s)
+				}
+				if !attrs && fm.FileInfo != nil {
+					t.Fatalf("parseMapping(%q) attributes parsed, but disabled", s)
+				}
+				if srcGlob || attrs {
+					continue
+				}
+				// round trip
+				m := escapeMapping(fm.Src)
+				if fm.Dst != "" {
+					m += "=" + escapeMapping(fm.Dst)
+				}
+				got, err := parseMapping(m, false, false)
+				if err != nil {
+					t.Fatalf("parseMapping(%q) of escaped %q error = %v", m, s, err)
+				}
+				if got != fm {
+					t.Fatalf("parseMapping(%q) = %+v, want %+v", m, got, fm)
+				}
+			}
+		}
+	})
+}
+
+func Test_readInputs(t *testing.T) {
+	name := path.Join(t.TempDir(), "inputs")
+	err := os.WriteFile(name, []byte("# binaries\nout/test-example=/usr/bin/test-example\n\n  conf/=/etc/  \r\n#docs/\n"), 0644)
+	assert.NoErrorf(t, err, "WriteFile")
+
+	got, err := readInputs(name)
+	assert.NoError(t, err)
+	assert.Equal(t, StringSlice{"out/test-example=/usr/bin/test-example", "conf/=/etc/"}, got)
+
+	// quotes, spaces and glob metacharacters are literal, backslash escapes '=' and ':'
+	err = os.WriteFile(name, []byte("./it's a \"test\" [1]*.txt=/usr/share/test/a\\:b.txt\n./a\\=b.txt\n"), 0644)
+	assert.NoErrorf(t, err, "WriteFile")
+	got, err = readInputs(name)
+	assert.NoError(t, err)
+	if assert.Len(t, got, 2) {
+		fm, err := parseMapping(got[0], true, true)
+		assert.NoError(t, err)
+		assert.Equal(t, FileMap{Src: globEscape(`./it's a "test" [1]*.txt`), Dst: "/usr/share/test/a:b.txt"}, fm)
+		fm, err = parseMapping(got[1], true, true)
+		assert.NoError(t, err)
+		assert.Equal(t, FileMap{Src: "./a=b.txt"}, fm)
+	}
+
+	err = os.WriteFile(name, []byte("out/test-example\nout/test=/usr/bin/test=test\n"), 0644)
+	assert.NoErrorf(t, err, "WriteFile")
+	_, err = readInputs(name)
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), name+":2: filemap is invalid at position 23"), err.Error())
+	}
+}
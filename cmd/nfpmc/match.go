@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// matchPath reports whether name matches the shell pattern, where "**" element matches zero or more path elements.
+// Pattern without '/' is matched against the last element of name.
+func matchPath(pattern, name string) (bool, error) {
+	if !strings.Contains(pattern, "/") {
+		return path.Match(pattern, path.Base(name))
+	}
+	return matchElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true, nil
+			}
+			for i := 0; i <= len(name); i++ {
+				if ok, err := matchElems(pattern, name[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		if ok, err := path.Match(pattern[0], name[0]); !ok || err != nil {
+			return false, err
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+// validatePattern checks pattern syntax for matchPath
+func validatePattern(pattern string) error {
+	for _, elem := range strings.Split(pattern, "/") {
+		if _, err := path.Match(elem, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func Test_matchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "*.orig", name: "conf/test.conf.orig", want: true},
+		{pattern: "*.orig", name: "conf/test.conf", want: false},
+		{pattern: ".git", name: "src/.git", want: true},
+		{pattern: ".*.swp", name: "/etc/.test.conf.swp", want: true},
+		{pattern: "conf/*.orig", name: "conf/test.orig", want: true},
+		{pattern: "conf/*.orig", name: "root/conf/test.orig", want: false},
+		{pattern: "**/conf/*.orig", name: "root/conf/test.orig", want: true},
+		{pattern: "**/conf/*.orig", name: "conf/test.orig", want: true},
+		{pattern: "/usr/share/doc/**", name: "/usr/share/doc/test/README", want: true},
+		{pattern: "/usr/share/doc/**", name: "/usr/share/doc", want: true},
+		{pattern: "/usr/share/doc/**", name: "/usr/share/icons/test.png", want: false},
+		{pattern: "/usr/**/*.png", name: "/usr/share/icons/test.png", want: true},
+		{pattern: "/usr/**/*.png", name: "/usr/test.png", want: true},
+		{pattern: "/usr/**/*.png", name: "/opt/test.png", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			got, err := matchPath(tt.pattern, tt.name)
+			if err != nil {
+				t.Fatalf("matchPath() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validatePattern(t *testing.T) {
+	if err := validatePattern("/usr/**/[a-z]*.png"); err != nil {
+		t.Errorf("validatePattern() error = %v", err)
+	}
+	if err := validatePattern("/usr/[a-z*.png"); err == nil {
+		t.Errorf("validatePattern() must failed")
+	}
+}
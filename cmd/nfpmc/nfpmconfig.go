@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"dario.cat/mergo"
+	"github.com/goreleaser/nfpm/v2"
+	"github.com/goreleaser/nfpm/v2/files"
+)
+
+// loadConfig load nfpm config (environment variables are expanded) with overrides for output packager.
+// Values set from command line are not overwritten. Config contents are returned expanded (globs and trees).
+func (p *Packager) loadConfig() (files.Contents, error) {
+	if p.Config == "" {
+		return nil, fmt.Errorf("config not set")
+	}
+	cfg, err := nfpm.ParseFileWithEnvMapping(p.Config, os.Getenv)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Config, err)
+	}
+	info, err := cfg.Get(p.OutputType.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Config, err)
+	}
+
+	// relative sources are resolved from current dir, like nfpm does
+	contents, err := files.PrepareForPackager(info.Contents, info.Umask, p.OutputType.String(), info.DisableGlobbing, info.MTime)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Config, err)
+	}
+	expanded := make(files.Contents, 0, len(contents))
+	for _, c := range contents {
+		// parent dirs are added by packager
+		if c.Type != files.TypeImplicitDir {
+			expanded = append(expanded, c)
+		}
+	}
+	info.Contents = nil
+	info.DisableGlobbing = false
+
+	if p.Info.Version != "" {
+		// prerelease and metadata are parsed from config version
+		info.Prerelease = ""
+		info.VersionMetadata = ""
+	}
+	if err = mergo.Merge(&p.Info, *info); err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Config, err)
+	}
+	return expanded, nil
+}
+
+// addContents append contents, duplicate destinations are not allowed
+func (p *Packager) addContents(contents files.Contents) error {
+	for _, c := range contents {
+		c.Destination = path.Clean(c.Destination)
+		if c.Type == files.TypeFile {
+			// type can be set later (like --config-files)
+			c.Type = defaultStr
+		}
+		if _, ok := p.FilesMap[c.Destination]; ok {
+			return fmt.Errorf("filemap produce duplicate: %s", c.Destination)
+		}
+		p.Info.Contents = append(p.Info.Contents, c)
+		p.FilesMap[c.Destination] = c
+	}
+	return nil
+}
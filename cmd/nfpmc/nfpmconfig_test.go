@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNfpmConfig = `name: test
+version: ${TEST_NFPMC_VERSION}
+release: 2
+arch: amd64
+maintainer: Test <test@example.com>
+description: test package
+depends:
+  - common
+overrides:
+  deb:
+    depends:
+      - libc6 (>= 2.28)
+  rpm:
+    depends:
+      - glibc >= 2.28
+contents:
+  - src: ${TEST_NFPMC_DIR}/conf/*.conf
+    dst: /etc/test/
+    expand: true
+  - src: ${TEST_NFPMC_DIR}/share
+    dst: /usr/share/test
+    type: tree
+    expand: true
+  - src: ${TEST_NFPMC_DIR}/conf/a.conf
+    dst: /etc/test/rpm-only.conf
+    packager: rpm
+    expand: true
+scripts:
+  postinstall: @DIR@/postinst
+`
+
+func TestPackage_Config(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"conf/a.conf":        "a\n",
+		"conf/b.conf":        "b\n",
+		"share/data/content": "data\n",
+		"postinst":           "#!/bin/sh\n",
+		"nfpm.yaml":          strings.ReplaceAll(testNfpmConfig, "@DIR@", dir),
+	} {
+		name = path.Join(dir, name)
+		require.NoError(t, os.MkdirAll(path.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(data), 0644))
+	}
+	t.Setenv("TEST_NFPMC_DIR", dir)
+	t.Setenv("TEST_NFPMC_VERSION", "1.2.3-rc1")
+
+	destinations := func(p *Packager) []string {
+		var dests []string
+		for dest := range p.FilesMap {
+			dests = append(dests, dest)
+		}
+		sort.Strings(dests)
+		return dests
+	}
+
+	t.Run("rpm", func(t *testing.T) {
+		p := Packager{InputType: INPUT_NFPM, OutputType: RPM, Config: path.Join(dir, "nfpm.yaml"), OutDir: t.TempDir()}
+		require.NoError(t, p.Init())
+
+		assert.Equal(t, "test", p.Info.Name)
+		assert.Equal(t, "1.2.3", p.Info.Version)
+		assert.Equal(t, "rc1", p.Info.Prerelease)
+		assert.Equal(t, "2", p.Info.Release)
+		assert.Equal(t, "Test <test@example.com>", p.Info.Maintainer)
+		assert.Equal(t, []string{"glibc >= 2.28"}, p.Info.Depends)
+		assert.Equal(t, path.Join(dir, "postinst"), p.Info.Scripts.PostInstall)
+		assert.Equal(t, []string{
+			"/etc/test/a.conf",
+			"/etc/test/b.conf",
+			"/etc/test/rpm-only.conf",
+			"/usr/share/test",
+			"/usr/share/test/data",
+			"/usr/share/test/data/content",
+		}, destinations(&p))
+
+		// untyped contents are kept as in config
+		require.NoError(t, p.SetTypeRules(nil, false, false))
+		assert.Equal(t, defaultStr, p.FilesMap["/etc/test/a.conf"].Type)
+
+		require.NoError(t, p.SetConfigFiles(StringSlice{"/etc/test"}))
+		assert.Equal(t, configStr, p.FilesMap["/etc/test/a.conf"].Type)
+
+		require.NoError(t, p.Validate())
+		_, err := p.Do(false)
+		require.NoError(t, err)
+	})
+
+	t.Run("deb with overrides", func(t *testing.T) {
+		p := Packager{InputType: INPUT_NFPM, OutputType: DEB, Config: path.Join(dir, "nfpm.yaml"), OutDir: t.TempDir()}
+		p.Info.Version = "2.0.0"
+		p.Info.Maintainer = "CI <ci@example.com>"
+		require.NoError(t, p.Init())
+		require.NoError(t, p.SetDepends(StringSlice{"test-extra"}))
+
+		assert.Equal(t, "2.0.0", p.Info.Version)
+		assert.Equal(t, "", p.Info.Prerelease)
+		assert.Equal(t, "2", p.Info.Release)
+		assert.Equal(t, "CI <ci@example.com>", p.Info.Maintainer)
+		assert.Equal(t, "test package", p.Info.Description)
+		assert.Equal(t, []string{"libc6 (>= 2.28)", "test-extra"}, p.Info.Depends)
+		assert.NotContains(t, p.FilesMap, "/etc/test/rpm-only.conf")
+
+		require.NoError(t, p.AddFiles(StringSlice{path.Join(dir, "postinst") + "=/usr/lib/test/postinst"}))
+		assert.Contains(t, p.FilesMap, "/usr/lib/test/postinst")
+		assert.Error(t, p.AddFiles(StringSlice{path.Join(dir, "conf/a.conf") + "=/etc/test/a.conf"}))
+
+		require.NoError(t, p.Validate())
+		_, err := p.Do(false)
+		require.NoError(t, err)
+	})
+
+	t.Run("without release", func(t *testing.T) {
+		config := path.Join(dir, "norelease.yaml")
+		require.NoError(t, os.WriteFile(config, []byte(strings.Replace(strings.ReplaceAll(testNfpmConfig, "@DIR@", dir), "release: 2\n", "", 1)), 0644))
+
+		p := Packager{InputType: INPUT_NFPM, OutputType: RPM, Config: config, OutDir: t.TempDir()}
+		require.NoError(t, p.Init())
+		require.NoError(t, p.Validate())
+		assert.Equal(t, "", p.Info.Release)
+
+		_, err := p.Do(false)
+		require.NoError(t, err)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		p := Packager{InputType: INPUT_NFPM, OutputType: DEB}
+		assert.Error(t, p.Init())
+
+		p.Config = path.Join(dir, "missing.yaml")
+		assert.Error(t, p.Init())
+
+		require.NoError(t, os.WriteFile(path.Join(dir, "invalid.yaml"), []byte("unknown: field\n"), 0644))
+		p.Config = path.Join(dir, "invalid.yaml")
+		assert.Error(t, p.Init())
+	})
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// whiteoutPrefix mark deleted path in image layer
+	whiteoutPrefix = ".wh."
+	// whiteoutOpaque mark dir, which contents from lower layers are hidden
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// ociDescriptor is a OCI content descriptor (only used fields)
+type ociDescriptor struct {
+	Digest   string       `json:"digest"`
+	Platform *ociPlatform `json:"platform,omitempty"`
+}
+
+// ociPlatform is a image platform from OCI image index
+type ociPlatform struct {
+	Architecture string `json:"architecture"`
+}
+
+// ociIndex is a OCI image index (index.json in OCI layout) or image manifest (layers are set)
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// dockerManifest is a manifest.json entry from docker save tarball
+type dockerManifest struct {
+	Layers []string
+}
+
+// ociImage is a image tarball contents, staged in temporary dir
+type ociImage struct {
+	// blobs is a staged files by path inside tarball
+	blobs map[string]string
+	// links is a symlinks inside tarball (docker save link legacy layer paths to blobs)
+	links map[string]string
+}
+
+// addOCI add files from flattened layers of docker save or OCI layout tarball
+func (p *Packager) addOCI(fm FileMap) error {
+	return p.addArchives(fm, p.readOCI)
+}
+
+func (p *Packager) readOCI(name string, a *archiveImporter) error {
+	img, err := p.stageImage(name)
+	defer img.remove()
+	if err != nil {
+		return err
+	}
+	layers, err := img.layers(p.Info.Arch)
+	if err != nil {
+		return err
+	}
+	winners, targets, err := img.flatten(layers)
+	if err != nil {
+		return err
+	}
+	// lower layers are added first, so parent dirs are added before their contents
+	for i, layer := range layers {
+		a.targets = targets[i]
+		// seen is a number of entries by path in layer
+		seen := make(map[string]int)
+		err = img.readLayer(layer, func(tr *tar.Reader, hdr *tar.Header) error {
+			name := cleanEntryName(hdr.Name)
+			n := seen[name]
+			seen[name]++
+			winner, ok := winners[name]
+			return readLayerEntry(tr, hdr, a, ok && winner == ociWinner{layer: i, entry: n})
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", layer, err)
+		}
+	}
+	return nil
+}
+
+// readLayerEntry add layer entry if it's not shadowed by upper layers.
+// Hardlink targets are staged even if they are shadowed.
+func readLayerEntry(tr *tar.Reader, hdr *tar.Header, a *archiveImporter, winner bool) error {
+	if winner {
+		return readTarEntry(tr, hdr, a)
+	}
+	name := cleanEntryName(hdr.Name)
+	if hdr.Typeflag != tar.TypeReg || !a.targets[name] {
+		return nil
+	}
+	staged, err := a.p.stage(tr, hdr.ModTime)
+	if err != nil {
+		return fmt.Errorf("%s: %w", hdr.Name, err)
+	}
+	a.staged[name] = staged
+	return nil
+}
+
+// stageImage stage image tarball (may be compressed) files, blobs are read more than once
+func (p *Packager) stageImage(name string) (*ociImage, error) {
+	img := &ociImage{blobs: make(map[string]string), links: make(map[string]string)}
+	f, err := os.Open(name)
+	if err != nil {
+		return img, err
+	}
+	defer f.Close()
+
+	r, err := decompress(f)
+	if err != nil {
+		return img, err
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return img, nil
+		}
+		if err != nil {
+			return img, err
+		}
+		name := cleanEntryName(hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			if img.blobs[name], err = p.stage(tr, hdr.ModTime); err != nil {
+				return img, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+		case tar.TypeSymlink:
+			img.links[name] = cleanEntryName(path.Join(path.Dir(name), hdr.Linkname))
+		case tar.TypeLink:
+			img.links[name] = cleanEntryName(hdr.Linkname)
+		}
+	}
+}
+
+// remove remove staged image files
+func (img *ociImage) remove() {
+	for _, staged := range img.blobs {
+		os.Remove(staged)
+	}
+}
+
+// open open file from image tarball, symlinks are followed
+func (img *ociImage) open(name string) (*os.File, error) {
+	name = cleanEntryName(name)
+	for i := 0; i < 8; i++ {
+		if staged, ok := img.blobs[name]; ok {
+			return os.Open(staged)
+		}
+		target, ok := img.links[name]
+		if !ok {
+			break
+		}
+		name = target
+	}
+	return nil, fmt.Errorf("%s not found in image", name)
+}
+
+// readJSON decode json file from image tarball
+func (img *ociImage) readJSON(name string, v interface{}) error {
+	f, err := img.open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err = json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// blobName return blob path in OCI layout for digest
+func blobName(digest string) (string, error) {
+	alg, hex, ok := strings.Cut(digest, ":")
+	if !ok || alg == "" || hex == "" || strings.Contains(hex, "/") {
+		return "", fmt.Errorf("digest is invalid: %s", digest)
+	}
+	return path.Join("blobs", alg, hex), nil
+}
+
+// layers return image layers paths inside tarball (from lower to upper).
+// docker save manifest.json is used if exist, else OCI layout index.json.
+// Multi-platform image is resolved with arch (nfpm arch name), tarball must contain one image.
+func (img *ociImage) layers(arch string) ([]string, error) {
+	if _, err := img.open("manifest.json"); err == nil {
+		var manifests []dockerManifest
+		if err = img.readJSON("manifest.json", &manifests); err != nil {
+			return nil, err
+		}
+		if len(manifests) != 1 {
+			return nil, fmt.Errorf("image tarball must contain one image, got %d", len(manifests))
+		}
+		return manifests[0].Layers, nil
+	}
+
+	var index ociIndex
+	if err := img.readJSON("index.json", &index); err != nil {
+		return nil, fmt.Errorf("docker save manifest.json or OCI layout index.json not found")
+	}
+	// index may be nested (like for multi-platform images)
+	for i := 0; i < 8; i++ {
+		if index.Layers != nil {
+			layers := make([]string, 0, len(index.Layers))
+			for _, l := range index.Layers {
+				name, err := blobName(l.Digest)
+				if err != nil {
+					return nil, err
+				}
+				layers = append(layers, name)
+			}
+			return layers, nil
+		}
+		d, err := selectManifest(index.Manifests, arch)
+		if err != nil {
+			return nil, err
+		}
+		name, err := blobName(d.Digest)
+		if err != nil {
+			return nil, err
+		}
+		index = ociIndex{}
+		if err = img.readJSON(name, &index); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("image index nesting is too deep")
+}
+
+// ociArch convert nfpm arch name to OCI platform architecture
+func ociArch(arch string) string {
+	if goArch, ok := rpmArchToGo[arch]; ok {
+		arch = goArch
+	}
+	if strings.HasPrefix(arch, "arm") && arch != "arm64" {
+		// variant is not checked
+		return "arm"
+	}
+	return arch
+}
+
+// selectManifest select the only image manifest or manifest for arch (attestation manifests are skipped)
+func selectManifest(manifests []ociDescriptor, arch string) (ociDescriptor, error) {
+	var selected []ociDescriptor
+	for _, d := range manifests {
+		if d.Platform != nil && d.Platform.Architecture == "unknown" {
+			// buildkit attestation manifest
+			continue
+		}
+		selected = append(selected, d)
+	}
+	if len(selected) > 1 && arch != "" {
+		platforms := selected
+		selected = nil
+		for _, d := range platforms {
+			if d.Platform != nil && d.Platform.Architecture == ociArch(arch) {
+				selected = append(selected, d)
+			}
+		}
+	}
+	switch len(selected) {
+	case 0:
+		return ociDescriptor{}, fmt.Errorf("image manifest for arch %s not found", arch)
+	case 1:
+		return selected[0], nil
+	default:
+		return ociDescriptor{}, fmt.Errorf("image tarball must contain one image, got %d manifests (set --architecture for multi-platform image)", len(selected))
+	}
+}
+
+// readLayer read layer tar (may be compressed), fn is called for each entry
+func (img *ociImage) readLayer(layer string, fn func(tr *tar.Reader, hdr *tar.Header) error) error {
+	f, err := img.open(layer)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := decompress(f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(tr, hdr); err != nil {
+			return err
+		}
+	}
+}
+
+// ociWinner is a visible layer entry
+type ociWinner struct {
+	// layer is a layer index
+	layer int
+	// entry is a index of entry between entries with the same path in layer (last entry wins)
+	entry int
+}
+
+// flatten resolve layers (from upper to lower) with whiteouts, winners is a layer entry for visible paths,
+// targets is a hardlink targets of visible hardlinks by layer
+func (img *ociImage) flatten(layers []string) (winners map[string]ociWinner, targets []map[string]bool, err error) {
+	winners = make(map[string]ociWinner)
+	targets = make([]map[string]bool, len(layers))
+	// isDir is a type of visible paths (paths under files are hidden)
+	isDir := make(map[string]bool)
+	// hidden is a paths deleted in upper layers, opaque is a dirs with contents hidden in upper layers
+	hidden := make(map[string]bool)
+	opaque := make(map[string]bool)
+
+	visible := func(name string, layer int) bool {
+		if w, ok := winners[name]; (ok && w.layer != layer) || hidden[name] {
+			return false
+		}
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if hidden[dir] || opaque[dir] {
+				return false
+			}
+			if d, ok := isDir[dir]; ok && !d {
+				return false
+			}
+		}
+		// opaque root hide all lower layers
+		return !opaque[""]
+	}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		targets[i] = make(map[string]bool)
+		seen := make(map[string]int)
+		var whiteouts, opaques []string
+		err = img.readLayer(layers[i], func(_ *tar.Reader, hdr *tar.Header) error {
+			name := cleanEntryName(hdr.Name)
+			if name == "" {
+				return nil
+			}
+			base := path.Base(name)
+			if base == whiteoutOpaque {
+				opaques = append(opaques, path.Dir(name))
+				return nil
+			}
+			if strings.HasPrefix(base, whiteoutPrefix) {
+				whiteouts = append(whiteouts, path.Join(path.Dir(name), base[len(whiteoutPrefix):]))
+				return nil
+			}
+			n := seen[name]
+			seen[name]++
+			if !visible(name, i) {
+				return nil
+			}
+			winners[name] = ociWinner{layer: i, entry: n}
+			isDir[name] = hdr.Typeflag == tar.TypeDir
+			if hdr.Typeflag == tar.TypeLink {
+				targets[i][cleanEntryName(hdr.Linkname)] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", layers[i], err)
+		}
+		// whiteouts are applied to lower layers only
+		for _, name := range whiteouts {
+			hidden[cleanEntryName(name)] = true
+		}
+		for _, name := range opaques {
+			opaque[cleanEntryName(name)] = true
+		}
+	}
+	return winners, targets, nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTarEntry is a tar entry for test archives (regular file if Typeflag is not set)
+type testTarEntry struct {
+	Name     string
+	Typeflag byte
+	Linkname string
+	Data     string
+}
+
+func writeTestTarEntries(t *testing.T, entries []testTarEntry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.Name, Typeflag: e.Typeflag, Linkname: e.Linkname, Mode: 0644, Size: int64(len(e.Data)), ModTime: testTarMTime}
+		switch e.Typeflag {
+		case 0:
+			hdr.Typeflag = tar.TypeReg
+		case tar.TypeDir:
+			hdr.Mode = 0755
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(e.Data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+// testImageLayers is a test image layers (from lower to upper)
+var testImageLayers = [][]testTarEntry{
+	{
+		{Name: "etc/", Typeflag: tar.TypeDir},
+		{Name: "etc/app.conf", Data: "key = value\n"},
+		{Name: "etc/old.conf", Data: "old\n"},
+		{Name: "usr/", Typeflag: tar.TypeDir},
+		{Name: "usr/bin/", Typeflag: tar.TypeDir},
+		{Name: "usr/bin/app", Data: "app v1\n"},
+		{Name: "usr/bin/app-hard", Typeflag: tar.TypeLink, Linkname: "usr/bin/app"},
+		{Name: "var/", Typeflag: tar.TypeDir},
+		{Name: "var/cache/", Typeflag: tar.TypeDir},
+		{Name: "var/cache/old", Data: "old\n"},
+	},
+	{
+		{Name: "etc/app.conf", Data: "key = stale\n"},
+		{Name: "etc/.wh.old.conf"},
+		{Name: "usr/bin/app", Data: "app v2\n"},
+		{Name: "usr/bin/app-link", Typeflag: tar.TypeSymlink, Linkname: "app"},
+		{Name: "var/cache/", Typeflag: tar.TypeDir},
+		{Name: "var/cache/.wh..wh..opq"},
+		{Name: "var/cache/new", Data: "new\n"},
+		// last entry wins
+		{Name: "etc/app.conf", Data: "key = new\n"},
+	},
+}
+
+// writeDockerImage write docker save tarball (legacy layout with layer dirs)
+func writeDockerImage(t *testing.T, name string) {
+	var (
+		entries  []testTarEntry
+		manifest = []dockerManifest{{}}
+	)
+	for i, layer := range testImageLayers {
+		layerName := path.Join(string(rune('a'+i)), "layer.tar")
+		entries = append(entries, testTarEntry{Name: layerName, Data: string(writeTestTarEntries(t, layer))})
+		manifest[0].Layers = append(manifest[0].Layers, layerName)
+	}
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	entries = append(entries, testTarEntry{Name: "manifest.json", Data: string(data)})
+	require.NoError(t, os.WriteFile(name, writeTestTarEntries(t, entries), 0644))
+}
+
+// writeOCIImage write gzipped OCI layout tarball with multi-platform index
+func writeOCIImage(t *testing.T, name string) {
+	var entries []testTarEntry
+	addBlob := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		digest := "sha256:" + hex.EncodeToString(sum[:])
+		entries = append(entries, testTarEntry{Name: "blobs/sha256/" + hex.EncodeToString(sum[:]), Data: string(data)})
+		return digest
+	}
+	addJSON := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return addBlob(data)
+	}
+
+	var manifest ociIndex
+	for _, layer := range testImageLayers {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(writeTestTarEntries(t, layer))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		manifest.Layers = append(manifest.Layers, ociDescriptor{Digest: addBlob(buf.Bytes())})
+	}
+	// other platform image is empty
+	other := ociIndex{Layers: []ociDescriptor{{Digest: addBlob(writeTestTarEntries(t, nil))}}}
+
+	index := ociIndex{Manifests: []ociDescriptor{
+		{Digest: addJSON(other), Platform: &ociPlatform{Architecture: "amd64"}},
+		{Digest: addJSON(manifest), Platform: &ociPlatform{Architecture: "arm64"}},
+		{Digest: addJSON(ociIndex{Layers: []ociDescriptor{}}), Platform: &ociPlatform{Architecture: "unknown"}},
+	}}
+	root := ociIndex{Manifests: []ociDescriptor{{Digest: addJSON(index)}}}
+	data, err := json.Marshal(root)
+	require.NoError(t, err)
+	entries = append(entries, testTarEntry{Name: "index.json", Data: string(data)}, testTarEntry{Name: "oci-layout", Data: `{"imageLayoutVersion":"1.0.0"}`})
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err = w.Write(writeTestTarEntries(t, entries))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(name, buf.Bytes(), 0644))
+}
+
+func TestPackage_AddFilesOCI(t *testing.T) {
+	dir := t.TempDir()
+	writeDockerImage(t, path.Join(dir, "docker.tar"))
+	writeOCIImage(t, path.Join(dir, "oci.tar.gz"))
+
+	readSource := func(t *testing.T, p *Packager, dest string) string {
+		data, err := os.ReadFile(p.FilesMap[dest].Source)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("docker", func(t *testing.T) {
+		p := Packager{InputType: INPUT_OCI, OutputType: DEB, Dir: dir, OutDir: t.TempDir()}
+		p.Info.Name = "test"
+		p.Info.Version = "1.0.0"
+		p.Info.Release = "1"
+		require.NoError(t, p.Init())
+		defer p.Close()
+
+		require.NoError(t, p.AddFiles(StringSlice{"docker.tar"}))
+
+		var dests []string
+		for dest := range p.FilesMap {
+			dests = append(dests, dest)
+		}
+		sort.Strings(dests)
+		assert.Equal(t, []string{
+			"/etc/app.conf",
+			"/usr/bin/app",
+			"/usr/bin/app-hard",
+			"/usr/bin/app-link",
+			"/var/cache/new",
+		}, dests)
+		assert.Equal(t, "key = new\n", readSource(t, &p, "/etc/app.conf"))
+		assert.Equal(t, "app v2\n", readSource(t, &p, "/usr/bin/app"))
+		assert.Equal(t, "app v1\n", readSource(t, &p, "/usr/bin/app-hard"))
+		assert.Equal(t, symlinkStr, p.FilesMap["/usr/bin/app-link"].Type)
+
+		require.NoError(t, p.Validate())
+		_, err := p.Do(false)
+		require.NoError(t, err)
+	})
+
+	t.Run("oci", func(t *testing.T) {
+		p := Packager{InputType: INPUT_OCI, OutputType: RPM, Dir: dir}
+		p.Info.Name = "test"
+		p.Info.Version = "1.0.0"
+		p.Info.Release = "1"
+		p.Info.Arch = "aarch64"
+		require.NoError(t, p.Init())
+		defer p.Close()
+
+		require.NoError(t, p.AddFiles(StringSlice{"oci.tar.gz/usr/bin/app*=/opt/test/bin", "oci.tar.gz/etc=/etc/test"}))
+
+		assert.Len(t, p.FilesMap, 4)
+		assert.Equal(t, "app v2\n", readSource(t, &p, "/opt/test/bin/app"))
+		assert.Equal(t, "app v1\n", readSource(t, &p, "/opt/test/bin/app-hard"))
+		assert.Equal(t, "app", p.FilesMap["/opt/test/bin/app-link"].Source)
+		assert.Equal(t, "key = new\n", readSource(t, &p, "/etc/test/app.conf"))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(dir, "invalid.tar"), writeTestTarEntries(t, []testTarEntry{{Name: "file"}}), 0644))
+
+		p := Packager{InputType: INPUT_OCI, OutputType: DEB, Dir: dir}
+		p.Info.Name = "test"
+		p.Info.Version = "1.0.0"
+		p.Info.Release = "1"
+		require.NoError(t, p.Init())
+		defer p.Close()
+
+		assert.ErrorContains(t, p.AddFiles(StringSlice{"invalid.tar"}), "manifest.json or OCI layout index.json not found")
+
+		// multi-platform image without arch
+		p.Info.Arch = ""
+		assert.ErrorContains(t, p.AddFiles(StringSlice{"oci.tar.gz"}), "got 2 manifests")
+	})
+}
+
+func Test_blobName(t *testing.T) {
+	name, err := blobName("sha256:abc")
+	require.NoError(t, err)
+	assert.Equal(t, "blobs/sha256/abc", name)
+
+	for _, digest := range []string{"abc", "sha256:", "sha256:../../etc/passwd"} {
+		_, err = blobName(digest)
+		assert.Error(t, err, digest)
+	}
+}
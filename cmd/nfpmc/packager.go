@@ -6,6 +6,8 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -17,24 +19,54 @@ import (
 )
 
 const (
-	defaultStr = ""
-	configStr  = "config|noreplace"
-	symlinkStr = "symlink"
-	docStr     = "doc"
+	defaultStr       = ""
+	configStr        = "config|noreplace"
+	configReplaceStr = "config"
+	ghostStr         = "ghost"
+	symlinkStr       = "symlink"
+	docStr           = "doc"
+	dirStr           = "dir"
 )
 
 type InputType uint8
 
 const (
 	INPUT_DIR InputType = iota
+	INPUT_TAR
+	INPUT_ZIP
+	INPUT_DEB
+	INPUT_RPM
+	INPUT_EMPTY
+	INPUT_APK
+	INPUT_NFPM
+	INPUT_OCI
+	INPUT_GIT
 )
 
-var inputTypeStr = []string{"dir"}
+var inputTypeStr = []string{"dir", "tar", "zip", "deb", "rpm", "empty", "apk", "nfpm", "oci", "git"}
 
 func (i *InputType) Set(value string) error {
 	switch strings.ToLower(value) {
 	case "dir":
 		*i = INPUT_DIR
+	case "tar":
+		*i = INPUT_TAR
+	case "zip":
+		*i = INPUT_ZIP
+	case "deb":
+		*i = INPUT_DEB
+	case "rpm":
+		*i = INPUT_RPM
+	case "empty":
+		*i = INPUT_EMPTY
+	case "apk":
+		*i = INPUT_APK
+	case "nfpm":
+		*i = INPUT_NFPM
+	case "oci":
+		*i = INPUT_OCI
+	case "git":
+		*i = INPUT_GIT
 	default:
 		return fmt.Errorf("unknown input type")
 	}
@@ -49,6 +81,11 @@ func (i *InputType) Type() string {
 	return "intput_type"
 }
 
+// IsPackage return true for package input types (package metadata is imported with files)
+func (i *InputType) IsPackage() bool {
+	return *i == INPUT_DEB || *i == INPUT_RPM || *i == INPUT_APK
+}
+
 type OutputType uint8
 
 const (
@@ -125,10 +162,12 @@ func (u *StringMap) Type() string {
 	return "map[string]string"
 }
 
-// type FileMap struct {
-// 	Src string
-// 	Dst string
-// }
+// FileMap is a SRC[=DST[:MODE:OWNER:GROUP]] mapping
+type FileMap struct {
+	Src      string
+	Dst      string
+	FileInfo *files.ContentFileInfo
+}
 
 type FileContentMap map[string]*files.Content
 
@@ -137,6 +176,41 @@ type Packager struct {
 	OutputType OutputType
 	OutDir     string
 	OutName    string
+	// Config is a nfpm config file (for nfpm input)
+	Config string
+	// GitRef is a tree-ish (tag, branch or commit) for git input
+	GitRef string
+	// Dir is a base directory for relative sources (not scripts)
+	Dir string
+	// Prefix is a destination prefix for sources without explicit destination
+	Prefix string
+	// Exclude is a patterns for skip files and dirs (matched with source and destination path)
+	Exclude StringSlice
+	// FollowSymlinks is set for follow in-tree symlinks (instead of preserve)
+	FollowSymlinks bool
+	// IgnoreFiles is a gitignore-style files, applied to input dirs
+	IgnoreFiles StringSlice
+	// NoIgnoreFiles is set for skip .gitignore and .nfpmcignore files in input dirs
+	NoIgnoreFiles bool
+	ignoreRules   []ignoreRule
+	// NoDefaultTypeRules is set for skip built-in type rules (/etc is config, /usr/share/doc is doc, etc)
+	NoDefaultTypeRules bool
+	// DebNoDefaultConfigFiles is set for skip built-in config type rules for deb
+	DebNoDefaultConfigFiles bool
+	// Reproducible is set for reproducible build (see normalize)
+	Reproducible bool
+	// TemplateScripts is set for render scripts with text/template
+	TemplateScripts bool
+	templateValues  map[string]string
+	// stageDir is a temporary dir for files, extracted from archives
+	stageDir string
+	// Untranslated is a report of imported package fields, which could not be translated to output package
+	Untranslated []string
+	// default files owner and group for rpm and deb
+	RPMUser  string
+	RPMGroup string
+	DebUser  string
+	DebGroup string
 
 	Info        nfpm.Info
 	Compression string
@@ -159,17 +233,45 @@ func charsToString(ca []int8) string {
 }
 
 func (p *Packager) Init() error {
-	if len(p.Info.Name) == 0 {
-		return fmt.Errorf("name not set")
+	var (
+		contents files.Contents
+		err      error
+	)
+	if p.InputType == INPUT_NFPM {
+		if contents, err = p.loadConfig(); err != nil {
+			return err
+		}
 	}
-	if len(p.Info.Version) == 0 {
-		return fmt.Errorf("version not set")
+	if p.InputType == INPUT_GIT {
+		if err = p.setGitVersion(); err != nil {
+			return err
+		}
 	}
-	if len(p.Info.Release) == 0 {
-		return fmt.Errorf("iteration not set")
+	// package inputs metadata is imported from package
+	if !p.InputType.IsPackage() {
+		if len(p.Info.Name) == 0 {
+			return fmt.Errorf("name not set")
+		}
+		if len(p.Info.Version) == 0 {
+			return fmt.Errorf("version not set")
+		}
+		// release is optional in nfpm config
+		if len(p.Info.Release) == 0 && p.InputType != INPUT_NFPM {
+			return fmt.Errorf("iteration not set")
+		}
+	}
+	// destinations and scripts are rendered with the same version and release
+	p.normalizeVersion()
+	if len(p.Info.Epoch) > 0 {
+		if _, err := strconv.ParseUint(p.Info.Epoch, 10, 32); err != nil {
+			return fmt.Errorf("epoch is invalid: %s", p.Info.Epoch)
+		}
 	}
 
-	if p.Info.Arch == "" {
+	if p.Info.Arch == "" && p.InputType == INPUT_EMPTY {
+		// meta-package without files is architecture independent
+		p.Info.Arch = "all"
+	} else if p.Info.Arch == "" && !p.InputType.IsPackage() {
 		var buf syscall.Utsname
 		err := syscall.Uname(&buf)
 		if err != nil {
@@ -185,12 +287,32 @@ func (p *Packager) Init() error {
 		}
 	}
 
+	for _, pattern := range p.Exclude {
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("exclude pattern is invalid: %s", pattern)
+		}
+	}
+
+	p.ignoreRules = nil
+	for _, name := range p.IgnoreFiles {
+		rules, err := readIgnore(name, false)
+		if err != nil {
+			return err
+		}
+		p.ignoreRules = append(p.ignoreRules, rules...)
+	}
+
+	// contents already expanded by AddFiles
+	p.Info.DisableGlobbing = true
+
 	p.FilesMap = make(FileContentMap)
 
-	return nil
+	return p.addContents(contents)
 }
 
-func (p *Packager) Validate() error {
+// normalizeVersion split VERSION-RELEASE (or VERSION_RELEASE), if release is not set (default 0 from command line),
+// and parse version like nfpm does (1.0-rc1 is 1.0.0 with prerelease rc1), so templates are rendered with final values
+func (p *Packager) normalizeVersion() {
 	if p.Info.Release == "0" {
 		sv := strings.IndexAny(p.Info.Version, "-_")
 		if sv > 1 {
@@ -199,9 +321,31 @@ func (p *Packager) Validate() error {
 			p.Info.Release = v[sv+1:]
 		}
 	}
-	if p.Info.Release == "" {
+	if p.Info.Version == "" {
+		return
+	}
+	info := p.Info
+	nfpm.WithDefaults(&info)
+	p.Info.Version, p.Info.Prerelease, p.Info.VersionMetadata = info.Version, info.Prerelease, info.VersionMetadata
+}
+
+func (p *Packager) Validate() error {
+	// version may be imported from package
+	p.normalizeVersion()
+	if p.Info.Release == "" && p.InputType != INPUT_NFPM {
+		// release from nfpm config is used as is
 		p.Info.Release = "1"
 	}
+	if p.Info.Section == "" && p.OutputType == DEB {
+		// section may be not imported (like from rpm), empty control field is invalid
+		p.Info.Section = "misc"
+	}
+
+	if p.Reproducible {
+		if err := p.normalize(); err != nil {
+			return err
+		}
+	}
 
 	nfpm.WithDefaults(&p.Info)
 	return p.Info.Validate()
@@ -216,6 +360,35 @@ func (p *Packager) Validate() error {
 // 	return "", fmt.Errorf("can't rewrite %s", name)
 // }
 
+// globEscape quote glob metacharacters in name
+func globEscape(name string) string {
+	var sb strings.Builder
+	for _, c := range name {
+		if strings.ContainsRune(`*?[]\`, c) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// globLiteral unescape glob without metacharacters, return false if glob has metacharacters
+func globLiteral(glob string) (string, bool) {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch glob[i] {
+		case '*', '?', '[':
+			return "", false
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+		}
+		sb.WriteByte(glob[i])
+	}
+	return sb.String(), true
+}
+
 func isDir(name string) (bool, error) {
 	fi, err := os.Stat(name)
 	if err != nil {
@@ -227,101 +400,289 @@ func isDir(name string) (bool, error) {
 	return false, nil
 }
 
-func expandDir(dir string) ([]string, error) {
-	var files []string
+// expander walk sources of the FileMap and resolve destinations
+type expander struct {
+	p  *Packager
+	fm FileMap
+	// src is a source glob, resolved with Packager.Dir
+	src string
+	// relative is set when source resolved with Packager.Dir
+	relative bool
+	// root is a expanded source prefix, replaced by destination
+	root string
+	// parents is a walked dirs stack (for detect symlink loops)
+	parents []os.FileInfo
+	// ignores is a gitignore-style rules stack
+	ignores []ignoreFrame
+}
+
+func newExpander(p *Packager, fm FileMap) *expander {
+	e := &expander{p: p, fm: fm, src: fm.Src}
+	if len(p.Dir) > 0 && !path.IsAbs(fm.Src) {
+		e.relative = true
+		e.src = path.Join(globEscape(p.Dir), fm.Src)
+	}
+	return e
+}
+
+// rel return source path relative to Packager.Dir
+func (e *expander) rel(file string) (string, error) {
+	if e.relative {
+		return filepath.Rel(e.p.Dir, file)
+	}
+	return file, nil
+}
+
+func (e *expander) destination(file string) (string, error) {
+	if e.fm.Dst == "" {
+		rel, err := e.rel(file)
+		if err != nil {
+			return "", err
+		}
+		return path.Join("/", e.p.Prefix, rel), nil
+	}
+	return strings.Replace(file, e.root, e.fm.Dst, 1), nil
+}
+
+// dirDestination resolve package path for expanded source dir
+func (e *expander) dirDestination(dir string) (string, error) {
+	dest, err := e.destination(dir + "/")
+	if err != nil || dest == "/" {
+		return dest, err
+	}
+	return strings.TrimSuffix(dest, "/"), nil
+}
+
+// excluded check source and destination path with Packager.Exclude patterns
+func (e *expander) excluded(file string, isDir bool) (bool, error) {
+	if len(e.p.Exclude) == 0 {
+		return false, nil
+	}
+	src, err := e.rel(file)
+	if err != nil {
+		return false, err
+	}
+	var dest string
+	if isDir {
+		dest, err = e.dirDestination(file)
+	} else {
+		dest, err = e.destination(file)
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, pattern := range e.p.Exclude {
+		if ok, err := matchPath(pattern, src); ok || err != nil {
+			return ok, err
+		}
+		if ok, err := matchPath(pattern, dest); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// sourceFile is a expanded source
+type sourceFile struct {
+	Path string
+	Type string
+	// Target is a symlink target
+	Target string
+}
+
+// add append file or walk dir, excluded paths are pruned.
+// In-tree symlinks are preserved, top-level (explicitly given) symlinks are followed.
+func (e *expander) add(sources []sourceFile, file string, top bool) ([]sourceFile, error) {
+	fi, err := os.Lstat(file)
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if st, err := os.Stat(file); err == nil && (e.p.FollowSymlinks || top) {
+			fi = st
+		}
+	}
+	if !top {
+		if skip, err := ignored(e.ignores, file, fi.IsDir()); err != nil {
+			return nil, err
+		} else if skip {
+			return sources, nil
+		}
+	}
+	if skip, err := e.excluded(file, fi.IsDir()); err != nil {
+		return nil, err
+	} else if skip {
+		return sources, nil
+	}
+	if fi.IsDir() {
+		if top && len(e.p.ignoreRules) > 0 {
+			// explicit ignore files are relative to input dir and has lowest precedence
+			e.ignores = append(e.ignores, ignoreFrame{base: path.Clean(file), rules: e.p.ignoreRules})
+			defer func() { e.ignores = e.ignores[:len(e.ignores)-1] }()
+		}
+		return e.expandDir(sources, file, fi)
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(file)
+		if err != nil {
+			return nil, err
+		}
+		return append(sources, sourceFile{Path: file, Type: symlinkStr, Target: target}), nil
+	}
+	return append(sources, sourceFile{Path: file, Type: defaultStr}), nil
+}
+
+func (e *expander) expandDir(sources []sourceFile, dir string, fi os.FileInfo) ([]sourceFile, error) {
+	// followed symlinks can produce loop
+	for _, parent := range e.parents {
+		if os.SameFile(parent, fi) {
+			return nil, fmt.Errorf("symlink loop detected: %s", dir)
+		}
+	}
+	e.parents = append(e.parents, fi)
+	defer func() { e.parents = e.parents[:len(e.parents)-1] }()
+
+	if !e.p.NoIgnoreFiles {
+		n := len(e.ignores)
+		for _, name := range ignoreFileNames {
+			rules, err := readIgnore(path.Join(dir, name), true)
+			if err != nil {
+				return nil, err
+			}
+			if len(rules) > 0 {
+				e.ignores = append(e.ignores, ignoreFrame{base: path.Clean(dir), rules: rules})
+			}
+		}
+		defer func() { e.ignores = e.ignores[:n] }()
+	}
 
 	fs, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
+	if len(fs) == 0 {
+		// keep empty dir
+		return append(sources, sourceFile{Path: dir, Type: dirStr}), nil
+	}
 
 	for _, f := range fs {
-		fName := path.Join(dir, f.Name())
-		if ok, err := isDir(fName); err != nil {
+		if sources, err = e.add(sources, path.Join(dir, f.Name()), false); err != nil {
 			return nil, err
-		} else if ok {
-			if filesDir, err := expandDir(fName); err == nil {
-				files = append(files, filesDir...)
-			} else {
-				return nil, err
-			}
-		} else {
-			files = append(files, fName)
 		}
 	}
 
-	return files, nil
+	return sources, nil
 }
 
-func expand(glob string) (string, []string, error) {
-	var files []string
-	var root string
+func (e *expander) expand() ([]sourceFile, error) {
+	var sources []sourceFile
 
-	fs, err := filepath.Glob(glob)
+	fs, err := filepath.Glob(e.src)
 	if err != nil {
-		return root, nil, err
+		return nil, err
 	}
 	if len(fs) > 0 {
 		if ok, err := isDir(fs[0]); err != nil {
-			return fs[0], nil, err
+			return nil, err
 		} else if ok {
 			if strings.HasSuffix(fs[0], "/") {
-				root = fs[0]
+				e.root = fs[0]
 			} else {
-				root = fs[0] + "/"
+				e.root = fs[0] + "/"
 			}
-		} else if len(fs) == 1 && glob == fs[0] {
-			root = fs[0]
+		} else if lit, ok := globLiteral(e.src); ok && len(fs) == 1 && lit == fs[0] {
+			e.root = fs[0]
 		} else {
-			root = path.Dir(fs[0]) + "/"
+			e.root = path.Dir(fs[0]) + "/"
 		}
 	}
 
 	for _, file := range fs {
-		if ok, err := isDir(file); err != nil {
-			return root, nil, err
-		} else if ok {
-			if filesDir, err := expandDir(file); err == nil {
-				files = append(files, filesDir...)
-			} else {
-				return root, nil, err
-			}
-		} else {
-			files = append(files, file)
+		if sources, err = e.add(sources, file, true); err != nil {
+			return nil, err
 		}
 	}
-	return root, files, nil
+	return sources, nil
 }
 
 func (p *Packager) AddFiles(fileS StringSlice) error {
+	if p.InputType == INPUT_EMPTY && len(fileS) > 0 {
+		return fmt.Errorf("files not allowed for empty input type")
+	}
 	for _, f := range fileS {
-		fileRemap := strings.Split(f, "=")
-		if len(fileRemap) > 2 {
-			return fmt.Errorf("filemap is invalid: %s", f)
+		fm, err := parseMapping(f, true, true)
+		if err != nil {
+			return err
+		}
+		if fm.Dst, err = p.render(f, fm.Dst); err != nil {
+			return err
+		}
+		switch p.InputType {
+		case INPUT_TAR:
+			if err = p.addTar(fm); err != nil {
+				return err
+			}
+			continue
+		case INPUT_ZIP:
+			if err = p.addZip(fm); err != nil {
+				return err
+			}
+			continue
+		case INPUT_DEB:
+			if err = p.addDeb(fm); err != nil {
+				return err
+			}
+			continue
+		case INPUT_RPM:
+			if err = p.addRPM(fm); err != nil {
+				return err
+			}
+			continue
+		case INPUT_APK:
+			if err = p.addApk(fm); err != nil {
+				return err
+			}
+			continue
+		case INPUT_OCI:
+			if err = p.addOCI(fm); err != nil {
+				return err
+			}
+			continue
+		case INPUT_GIT:
+			if err = p.addGit(fm); err != nil {
+				return err
+			}
+			continue
 		}
 
-		// var v string
-		// if len(fileRemap) > 1 {
-		// 	v = fileRemap[1]
-		// } else {
-		// 	v = "/" + fileRemap[0]
-		// }
-
-		root, fs, err := expand(fileRemap[0])
+		e := newExpander(p, fm)
+		sources, err := e.expand()
 		if err != nil {
 			return err
 		}
-		for _, file := range fs {
+		for _, src := range sources {
 			var dest string
-			if len(fileRemap) == 1 {
-				dest = "/" + file
-			} else {
-				dest = strings.Replace(file, root, fileRemap[1], 1)
+			c := &files.Content{Source: src.Path, Type: src.Type, FileInfo: p.fileInfo(fm.FileInfo)}
+			if src.Type != defaultStr && c.FileInfo != nil {
+				// mode from filemap is for files
+				c.FileInfo.Mode = 0
+			}
+			switch src.Type {
+			case dirStr:
+				dest, err = e.dirDestination(src.Path)
+			case symlinkStr:
+				c.Source = src.Target
+				dest, err = e.destination(src.Path)
+			default:
+				dest, err = e.destination(src.Path)
+			}
+			if err != nil {
+				return err
 			}
 			if _, ok := p.FilesMap[dest]; ok {
 				return fmt.Errorf("filemap produce duplicate: %s", dest)
 			}
-			c := &files.Content{Source: file, Destination: dest, Type: defaultStr}
+			c.Destination = dest
 			p.Info.Contents = append(p.Info.Contents, c)
 			p.FilesMap[dest] = c
 		}
@@ -329,35 +690,99 @@ func (p *Packager) AddFiles(fileS StringSlice) error {
 	return nil
 }
 
+// AddDirectories mark directories (and subdirectories with contents) as owned by package
+func (p *Packager) AddDirectories(dirs StringSlice) error {
+	for _, d := range dirs {
+		var dest string
+		if path.IsAbs(d) {
+			dest = path.Clean(d)
+		} else {
+			dest = path.Join("/", p.Prefix, d)
+		}
+		if dest == "/" {
+			return fmt.Errorf("directory is invalid: %s", d)
+		}
+
+		owned := map[string]bool{dest: true}
+		dpath := dest + "/"
+		for k := range p.FilesMap {
+			if strings.HasPrefix(k, dpath) {
+				for dir := path.Dir(k); dir != dest; dir = path.Dir(dir) {
+					owned[dir] = true
+				}
+			}
+		}
+		ownedDirs := make([]string, 0, len(owned))
+		for dir := range owned {
+			ownedDirs = append(ownedDirs, dir)
+		}
+		sort.Strings(ownedDirs)
+
+		for _, dir := range ownedDirs {
+			if c, ok := p.FilesMap[dir]; ok {
+				if c.Type == dirStr {
+					continue
+				}
+				return fmt.Errorf("directory try to overwrite existing: %s", dir)
+			}
+			c := &files.Content{Destination: dir, Type: dirStr, FileInfo: p.fileInfo(nil)}
+			p.Info.Contents = append(p.Info.Contents, c)
+			p.FilesMap[dir] = c
+		}
+	}
+	return nil
+}
+
 func (p *Packager) AddSymlinks(fileS StringSlice) error {
 	for _, f := range fileS {
-		fileRemap := strings.Split(f, "=")
-		if len(fileRemap) != 2 {
+		fm, err := parseMapping(f, false, false)
+		if err != nil {
+			return err
+		}
+		if fm.Dst, err = p.render(f, fm.Dst); err != nil {
+			return err
+		}
+		if fm.Dst == "" {
 			return fmt.Errorf("symlink is invalid: %s", f)
 		}
-		if _, ok := p.FilesMap[fileRemap[1]]; ok {
-			return fmt.Errorf("symlink try to overwrite existing: %s", fileRemap[1])
+		dest := fm.Dst
+		if !path.IsAbs(dest) {
+			dest = path.Join("/", p.Prefix, dest)
+		}
+		if _, ok := p.FilesMap[dest]; ok {
+			return fmt.Errorf("symlink try to overwrite existing: %s", dest)
 		}
-		c := &files.Content{Source: fileRemap[0], Destination: fileRemap[1], Type: symlinkStr}
+		c := &files.Content{Source: fm.Src, Destination: dest, Type: symlinkStr, FileInfo: p.fileInfo(nil)}
 		p.Info.Contents = append(p.Info.Contents, c)
-		p.FilesMap[fileRemap[1]] = c
+		p.FilesMap[dest] = c
 	}
 	return nil
 }
 
+// matchFiles return files matched by path (file itself or files inside dir)
+func (p *Packager) matchFiles(f string) []*files.Content {
+	var (
+		dpath   string
+		matched []*files.Content
+	)
+	if strings.HasSuffix(f, "/") {
+		dpath = f
+	} else {
+		dpath = f + "/"
+	}
+	for k, v := range p.FilesMap {
+		if k == f || strings.HasPrefix(k, dpath) {
+			matched = append(matched, v)
+		}
+	}
+	return matched
+}
+
 func (p *Packager) setFiles(filesSet StringSlice, typ string) error {
 	for _, f := range filesSet {
-		var dpath string
-		if strings.HasSuffix(f, "/") {
-			dpath = f
-		} else {
-			dpath = f + "/"
-		}
-		for k, v := range p.FilesMap {
-			if k == f || strings.HasPrefix(k, dpath) {
-				if v.Type == "" {
-					v.Type = typ
-				}
+		for _, v := range p.matchFiles(f) {
+			if v.Type == "" {
+				v.Type = typ
 			}
 		}
 	}
@@ -368,7 +793,38 @@ func (p *Packager) SetConfigFiles(filesSet StringSlice) error {
 	return p.setFiles(filesSet, configStr)
 }
 
+// SetConfigReplaceFiles mark files as config, replaced on upgrade (rpm %config without noreplace)
+func (p *Packager) SetConfigReplaceFiles(filesSet StringSlice) error {
+	return p.setFiles(filesSet, configReplaceStr)
+}
+
+// SetGhostFiles mark files as ghost (rpm %ghost, owned, but not shipped), not existing files are added
+func (p *Packager) SetGhostFiles(filesSet StringSlice) error {
+	for _, f := range filesSet {
+		if matched := p.matchFiles(f); len(matched) > 0 {
+			for _, v := range matched {
+				if v.Type == "" {
+					v.Type = ghostStr
+				}
+			}
+			continue
+		}
+		if !path.IsAbs(f) {
+			return fmt.Errorf("ghost file must be absolute: %s", f)
+		}
+		dest := path.Clean(f)
+		c := &files.Content{Destination: dest, Type: ghostStr, FileInfo: p.fileInfo(nil)}
+		p.Info.Contents = append(p.Info.Contents, c)
+		p.FilesMap[dest] = c
+	}
+	return nil
+}
+
 func (p *Packager) SetDocFiles(filesSet StringSlice) error {
+	// doc is rpm only type, other packagers skip such files
+	if p.OutputType != RPM {
+		return nil
+	}
 	return p.setFiles(filesSet, docStr)
 }
 
@@ -378,15 +834,38 @@ func (p *Packager) formatOutName(packager nfpm.Packager) string {
 		return packager.ConventionalFileName(&p.Info)
 	}
 
-	s = strings.ReplaceAll(s, "NAME", p.Info.Name)
-	s = strings.ReplaceAll(s, "VERSION", p.Info.Version)
-	s = strings.ReplaceAll(s, "ITERATION", p.Info.Release)
-	s = strings.ReplaceAll(s, "ARCH", p.Info.Arch)
-	s = strings.ReplaceAll(s, "PLATFORM", p.Info.Platform)
+	s = replaceToken(s, "EPOCH", p.Info.Epoch)
+	s = replaceToken(s, "NAME", p.Info.Name)
+	s = replaceToken(s, "VERSION", p.Info.Version)
+	s = replaceToken(s, "ITERATION", p.Info.Release)
+	s = replaceToken(s, "ARCH", p.Info.Arch)
+	s = replaceToken(s, "PLATFORM", p.Info.Platform)
 
 	return s
 }
 
+// replaceToken replace token in package name, separator next to empty token is dropped
+// (separator after token, like in EPOCH:VERSION, or before token, if it's followed by extension or end of name)
+func replaceToken(s, token, value string) string {
+	if value != "" {
+		return strings.ReplaceAll(s, token, value)
+	}
+	for {
+		i := strings.Index(s, token)
+		if i == -1 {
+			return s
+		}
+		j := i + len(token)
+		switch {
+		case j < len(s) && strings.IndexByte("-_:", s[j]) != -1:
+			j++
+		case i > 0 && strings.IndexByte("-_:.", s[i-1]) != -1:
+			i--
+		}
+		s = s[:i] + s[j:]
+	}
+}
+
 func fileExists(filename string) bool {
 	info, err := os.Stat(filename)
 	if os.IsNotExist(err) {
@@ -429,12 +908,37 @@ func (p *Packager) Do(overwrite bool) (string, error) {
 		}
 	}
 
+	info := &p.Info
+	if p.TemplateScripts {
+		// render to copy, so original scripts paths are preserved
+		rendered := p.Info
+		scripts := map[string]*string{
+			"preinstall":      &rendered.Scripts.PreInstall,
+			"postinstall":     &rendered.Scripts.PostInstall,
+			"preremove":       &rendered.Scripts.PreRemove,
+			"postremove":      &rendered.Scripts.PostRemove,
+			"rpm-pretrans":    &rendered.RPM.Scripts.PreTrans,
+			"rpm-posttrans":   &rendered.RPM.Scripts.PostTrans,
+			"apk-preupgrade":  &rendered.APK.Scripts.PreUpgrade,
+			"apk-postupgrade": &rendered.APK.Scripts.PostUpgrade,
+		}
+		dir, err := os.MkdirTemp("", "nfpmc-scripts")
+		if err != nil {
+			return p.Info.Target, err
+		}
+		defer os.RemoveAll(dir)
+		if err = p.renderScripts(scripts, dir); err != nil {
+			return p.Info.Target, err
+		}
+		info = &rendered
+	}
+
 	f, err := os.Create(p.Info.Target)
 	if err != nil {
 		return p.Info.Target, err
 	}
 
-	err = packager.Package(&p.Info, f)
+	err = packager.Package(info, f)
 	if err != nil {
 		f.Close()
 		os.Remove(p.Info.Target)
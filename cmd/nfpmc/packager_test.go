@@ -12,6 +12,7 @@ import (
 	_ "github.com/goreleaser/nfpm/v2/rpm"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // func Test_rewriteFileName(t *testing.T) {
@@ -133,4 +134,367 @@ func TestPackage(t *testing.T) {
 	assert.NoErrorf(t, err, "Package.SetDocFiles")
 
 	assert.Equalf(t, verifyContent, p.Info.Contents, "Package.SetDocFiles Contents mismatch")
+
+	// Set doc for deb, must be ignored (doc files are skipped by non-rpm packagers)
+	p.OutputType = DEB
+	err = p.SetDocFiles([]string{"/usr/bin/test-example"})
+	assert.NoErrorf(t, err, "Package.SetDocFiles")
+	assert.Equalf(t, defaultStr, p.FilesMap["/usr/bin/test-example"].Type, "Package.SetDocFiles for deb")
+}
+
+func TestPackager_formatOutName(t *testing.T) {
+	var p Packager
+	p.Info.Name = "test"
+	p.Info.Epoch = "2"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+	p.Info.Arch = "x86_64"
+
+	p.OutName = "NAME-EPOCH:VERSION-ITERATION.ARCH.rpm"
+	assert.Equal(t, "test-2:1.0.0-1.x86_64.rpm", p.formatOutName(nil))
+
+	p.OutName = "NAME-VERSION-ITERATION.ARCH.rpm"
+	assert.Equal(t, "test-1.0.0-1.x86_64.rpm", p.formatOutName(nil))
+
+	// separator next to empty token is dropped
+	p.Info.Epoch = ""
+	for outName, want := range map[string]string{
+		"NAME-EPOCH:VERSION-ITERATION.ARCH.rpm": "test-1.0.0-1.x86_64.rpm",
+		"NAME_EPOCH-VERSION.ARCH.rpm":           "test_1.0.0.x86_64.rpm",
+		"NAME-VERSION-EPOCH.ARCH.rpm":           "test-1.0.0.x86_64.rpm",
+		"EPOCH-NAME-VERSION":                    "test-1.0.0",
+		"NAME-VERSION-EPOCH":                    "test-1.0.0",
+	} {
+		p.OutName = outName
+		assert.Equal(t, want, p.formatOutName(nil), outName)
+	}
+}
+
+func TestPackage_AddFilesChdir(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	rootDir := path.Dir(path.Dir(path.Dir(filename)))
+	testDir := path.Join(rootDir, "test")
+
+	err := os.Chdir(rootDir)
+	assert.NoErrorf(t, err, "Chdir")
+
+	tests := []struct {
+		name  string
+		files []string
+		want  files.Contents
+	}{
+		{
+			name:  "relative",
+			files: []string{"out/test-example", "conf/=/etc/"},
+			want: files.Contents{
+				&files.Content{Source: "test/out/test-example", Destination: "/out/test-example", Type: defaultStr},
+				&files.Content{Source: "test/conf/test-example.conf", Destination: "/etc/test-example.conf", Type: defaultStr},
+			},
+		},
+		{
+			name:  "absolute",
+			files: []string{path.Join(testDir, "out/test-example") + "=/usr/bin/test-example", path.Join(testDir, "docs")},
+			want: files.Contents{
+				&files.Content{Source: path.Join(testDir, "out/test-example"), Destination: "/usr/bin/test-example", Type: defaultStr},
+				&files.Content{Source: path.Join(testDir, "docs/test-example.txt"), Destination: path.Join(testDir, "docs/test-example.txt"), Type: defaultStr},
+			},
+		},
+		{
+			name:  "glob",
+			files: []string{"out/*=/usr/bin/", "*/test-example.txt"},
+			want: files.Contents{
+				&files.Content{Source: "test/out/test-example", Destination: "/usr/bin/test-example", Type: defaultStr},
+				&files.Content{Source: "test/docs/test-example.txt", Destination: "/docs/test-example.txt", Type: defaultStr},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Packager{Dir: "test"}
+			p.Info.Name = "test"
+			p.Info.Version = "1.0.0"
+			p.Info.Release = "1"
+
+			err := p.Init()
+			assert.NoErrorf(t, err, "Package.Init")
+
+			err = p.AddFiles(tt.files)
+			assert.NoErrorf(t, err, "Package.AddFiles")
+			assert.Equalf(t, tt.want, p.Info.Contents, "Package.AddFiles Contents mismatch")
+		})
+	}
+}
+
+func TestPackage_AddFilesPrefix(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	testDir := path.Join(path.Dir(path.Dir(path.Dir(filename))), "test")
+
+	err := os.Chdir(testDir)
+	assert.NoErrorf(t, err, "Chdir")
+
+	p := Packager{Prefix: "/opt/test"}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+
+	err = p.Init()
+	assert.NoErrorf(t, err, "Package.Init")
+
+	err = p.AddFiles([]string{"out/test-example", "conf/=/etc/"})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+	err = p.AddSymlinks([]string{"/opt/test/out/test-example=bin/test-example", "/opt/test/out/test-example=/usr/bin/test-example"})
+	assert.NoErrorf(t, err, "Package.AddSymlinks")
+
+	verifyContent := files.Contents{
+		&files.Content{Source: "out/test-example", Destination: "/opt/test/out/test-example", Type: defaultStr},
+		&files.Content{Source: "conf/test-example.conf", Destination: "/etc/test-example.conf", Type: defaultStr},
+		&files.Content{Source: "/opt/test/out/test-example", Destination: "/opt/test/bin/test-example", Type: symlinkStr},
+		&files.Content{Source: "/opt/test/out/test-example", Destination: "/usr/bin/test-example", Type: symlinkStr},
+	}
+	assert.Equalf(t, verifyContent, p.Info.Contents, "Package.AddFiles Contents mismatch")
+
+	// Relocated symlink must not overwrite relocated file
+	err = p.AddSymlinks([]string{"/usr/bin/test-example=out/test-example"})
+	if err == nil {
+		t.Errorf("Package.AddSymlinks success, but already exist\n")
+	}
+}
+
+func TestPackage_AddFilesExclude(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"app/bin/test", "app/bin/.test.swp", "app/etc/test.conf", "app/etc/test.conf.orig",
+		"app/.git/config", "app/share/doc/README",
+	} {
+		err := os.MkdirAll(path.Join(dir, path.Dir(name)), 0755)
+		assert.NoErrorf(t, err, "MkdirAll")
+		err = os.WriteFile(path.Join(dir, name), []byte(name), 0644)
+		assert.NoErrorf(t, err, "WriteFile")
+	}
+
+	p := Packager{Dir: dir, Exclude: StringSlice{"*.orig", ".*.swp", ".git", "/usr/share/**"}}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+
+	err := p.Init()
+	assert.NoErrorf(t, err, "Package.Init")
+
+	err = p.AddFiles([]string{"app/bin/=/usr/bin/", "app/etc=/etc/test/", "app/share=/usr/share/test", "app/.git"})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+
+	verifyContent := files.Contents{
+		&files.Content{Source: path.Join(dir, "app/bin/test"), Destination: "/usr/bin/test", Type: defaultStr},
+		&files.Content{Source: path.Join(dir, "app/etc/test.conf"), Destination: "/etc/test/test.conf", Type: defaultStr},
+	}
+	assert.Equalf(t, verifyContent, p.Info.Contents, "Package.AddFiles Contents mismatch")
+
+	p.Exclude = StringSlice{"[a-z"}
+	err = p.Init()
+	if err == nil {
+		t.Errorf("Package.Init success, but exclude pattern is invalid\n")
+	}
+}
+
+func TestPackage_AddDirectories(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"var/log/test", "opt/test/bin"} {
+		err := os.MkdirAll(path.Join(dir, name), 0755)
+		assert.NoErrorf(t, err, "MkdirAll")
+	}
+	err := os.WriteFile(path.Join(dir, "opt/test/bin/test"), []byte("test"), 0755)
+	assert.NoErrorf(t, err, "WriteFile")
+
+	p := Packager{Dir: dir}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+
+	err = p.Init()
+	assert.NoErrorf(t, err, "Package.Init")
+
+	err = p.AddFiles([]string{"opt", "var/=/var/:0640:test:test"})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+
+	err = p.AddDirectories([]string{"/opt/test", "/var/lib/test", "/var/log/test"})
+	assert.NoErrorf(t, err, "Package.AddDirectories")
+
+	verifyContent := files.Contents{
+		&files.Content{Source: path.Join(dir, "opt/test/bin/test"), Destination: "/opt/test/bin/test", Type: defaultStr},
+		&files.Content{
+			Source: path.Join(dir, "var/log/test"), Destination: "/var/log/test", Type: dirStr,
+			FileInfo: &files.ContentFileInfo{Owner: "test", Group: "test"},
+		},
+		&files.Content{Destination: "/opt/test", Type: dirStr},
+		&files.Content{Destination: "/opt/test/bin", Type: dirStr},
+		&files.Content{Destination: "/var/lib/test", Type: dirStr},
+	}
+	assert.Equalf(t, verifyContent, p.Info.Contents, "Package.AddDirectories Contents mismatch")
+
+	err = p.AddDirectories([]string{"/opt/test/bin/test"})
+	if err == nil {
+		t.Errorf("Package.AddDirectories success, but file already exist\n")
+	}
+}
+
+func TestPackage_AddFilesSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	err := os.MkdirAll(path.Join(dir, "root/lib/test"), 0755)
+	assert.NoErrorf(t, err, "MkdirAll")
+	err = os.WriteFile(path.Join(dir, "root/lib/test/test.so.1"), []byte("test"), 0755)
+	assert.NoErrorf(t, err, "WriteFile")
+	err = os.Symlink("test.so.1", path.Join(dir, "root/lib/test/test.so"))
+	assert.NoErrorf(t, err, "Symlink")
+	err = os.Symlink("test", path.Join(dir, "root/lib/current"))
+	assert.NoErrorf(t, err, "Symlink")
+	err = os.Symlink("root", path.Join(dir, "current"))
+	assert.NoErrorf(t, err, "Symlink")
+
+	newPackager := func(follow bool) *Packager {
+		p := &Packager{Dir: dir, FollowSymlinks: follow}
+		p.Info.Name = "test"
+		p.Info.Version = "1.0.0"
+		p.Info.Release = "1"
+		err := p.Init()
+		assert.NoErrorf(t, err, "Package.Init")
+		return p
+	}
+
+	// Preserve in-tree symlinks, top-level symlink to dir is followed
+	p := newPackager(false)
+	err = p.AddFiles([]string{"current=/usr/"})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+	verifyContent := files.Contents{
+		&files.Content{Source: "test", Destination: "/usr/lib/current", Type: symlinkStr},
+		&files.Content{Source: "test.so.1", Destination: "/usr/lib/test/test.so", Type: symlinkStr},
+		&files.Content{Source: path.Join(dir, "current/lib/test/test.so.1"), Destination: "/usr/lib/test/test.so.1", Type: defaultStr},
+	}
+	assert.Equalf(t, verifyContent, p.Info.Contents, "Package.AddFiles Contents mismatch")
+
+	// Top-level symlink to file is followed
+	p = newPackager(false)
+	err = p.AddFiles([]string{"root/lib/test/test.so=/usr/lib/libtest.so"})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+	verifyContent = files.Contents{
+		&files.Content{Source: path.Join(dir, "root/lib/test/test.so"), Destination: "/usr/lib/libtest.so", Type: defaultStr},
+	}
+	assert.Equalf(t, verifyContent, p.Info.Contents, "Package.AddFiles Contents mismatch")
+
+	// Follow symlinks
+	p = newPackager(true)
+	err = p.AddFiles([]string{"root=/usr/"})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+	verifyContent = files.Contents{
+		&files.Content{Source: path.Join(dir, "root/lib/current/test.so"), Destination: "/usr/lib/current/test.so", Type: defaultStr},
+		&files.Content{Source: path.Join(dir, "root/lib/current/test.so.1"), Destination: "/usr/lib/current/test.so.1", Type: defaultStr},
+		&files.Content{Source: path.Join(dir, "root/lib/test/test.so"), Destination: "/usr/lib/test/test.so", Type: defaultStr},
+		&files.Content{Source: path.Join(dir, "root/lib/test/test.so.1"), Destination: "/usr/lib/test/test.so.1", Type: defaultStr},
+	}
+	assert.Equalf(t, verifyContent, p.Info.Contents, "Package.AddFiles Contents mismatch")
+
+	// Follow symlinks loop, must failed
+	err = os.Symlink("..", path.Join(dir, "root/lib/test/loop"))
+	assert.NoErrorf(t, err, "Symlink")
+	p = newPackager(true)
+	err = p.AddFiles([]string{"root=/usr/"})
+	if err == nil {
+		t.Errorf("Package.AddFiles success, but symlink loop exist\n")
+	}
+	// Loop symlink is preserved
+	p = newPackager(false)
+	err = p.AddFiles([]string{"root=/usr/"})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+	assert.Equalf(t, symlinkStr, p.FilesMap["/usr/lib/test/loop"].Type, "Package.AddFiles loop symlink")
+}
+
+func TestPackage_AddFilesEscaped(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(path.Join(dir, "a=b[1].txt"), []byte("test"), 0644)
+	assert.NoErrorf(t, err, "WriteFile")
+
+	p := Packager{Dir: dir}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+
+	err = p.Init()
+	assert.NoErrorf(t, err, "Package.Init")
+
+	err = p.AddFiles([]string{`a\=b\[1\].txt=/usr/share/test/a\:b.txt`, `'a=b[1].txt'="/usr/share/test/a=b[1].txt"`})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+
+	verifyContent := files.Contents{
+		&files.Content{Source: path.Join(dir, "a=b[1].txt"), Destination: "/usr/share/test/a:b.txt", Type: defaultStr},
+		&files.Content{Source: path.Join(dir, "a=b[1].txt"), Destination: "/usr/share/test/a=b[1].txt", Type: defaultStr},
+	}
+	assert.Equalf(t, verifyContent, p.Info.Contents, "Package.AddFiles Contents mismatch")
+}
+
+func TestPackage_SetGhostFiles(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	testDir := path.Join(path.Dir(path.Dir(path.Dir(filename))), "test")
+
+	err := os.Chdir(testDir)
+	assert.NoErrorf(t, err, "Chdir")
+
+	p := Packager{}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+
+	err = p.Init()
+	assert.NoErrorf(t, err, "Package.Init")
+
+	err = p.AddFiles([]string{"conf/=/etc/test/", "docs/=/var/log/test/"})
+	assert.NoErrorf(t, err, "Package.AddFiles")
+
+	err = p.SetGhostFiles([]string{"/var/log/test", "/var/run/test.pid"})
+	assert.NoErrorf(t, err, "Package.SetGhostFiles")
+	err = p.SetConfigReplaceFiles([]string{"/etc/test/"})
+	assert.NoErrorf(t, err, "Package.SetConfigReplaceFiles")
+	err = p.SetConfigFiles([]string{"/etc"})
+	assert.NoErrorf(t, err, "Package.SetConfigFiles")
+
+	verifyContent := files.Contents{
+		&files.Content{Source: "conf/test-example.conf", Destination: "/etc/test/test-example.conf", Type: configReplaceStr},
+		&files.Content{Source: "docs/test-example.txt", Destination: "/var/log/test/test-example.txt", Type: ghostStr},
+		&files.Content{Destination: "/var/run/test.pid", Type: ghostStr},
+	}
+	assert.Equalf(t, verifyContent, p.Info.Contents, "Package.SetGhostFiles Contents mismatch")
+
+	err = p.SetGhostFiles([]string{"var/run/test.sock"})
+	if err == nil {
+		t.Errorf("Package.SetGhostFiles success, but path is relative\n")
+	}
+}
+
+func TestPackage_Empty(t *testing.T) {
+	dir := t.TempDir()
+	script := path.Join(dir, "postinst")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho installed\n"), 0755))
+
+	for _, outputType := range []OutputType{RPM, DEB, APK} {
+		t.Run(outputType.String(), func(t *testing.T) {
+			p := Packager{InputType: INPUT_EMPTY, OutputType: outputType, OutDir: t.TempDir()}
+			p.Info.Name = "test-stack"
+			p.Info.Version = "1.0.0"
+			p.Info.Release = "1"
+			p.Info.Scripts.PostInstall = script
+
+			require.NoError(t, p.Init())
+			assert.Equal(t, "all", p.Info.Arch)
+
+			assert.Error(t, p.AddFiles(StringSlice{"conf/=/etc/test/"}))
+			require.NoError(t, p.AddFiles(nil))
+			require.NoError(t, p.SetDepends(StringSlice{"test-agent >= 1.0", "test-exporter"}))
+			require.NoError(t, p.SetProvides(StringSlice{"test-meta"}))
+			assert.Empty(t, p.Info.Contents)
+
+			require.NoError(t, p.Validate())
+			target, err := p.Do(false)
+			require.NoError(t, err)
+			_, err = os.Stat(target)
+			assert.NoError(t, err)
+		})
+	}
 }
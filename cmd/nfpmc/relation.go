@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// relation operators in fpm syntax, longest first
+var relationOps = []string{">=", "<=", "==", ">>", "<<", "=", ">", "<"}
+
+// parseRelation split relation like "libc6 >= 2.28" or "libc6 (>= 2.28)" to name, operator and version
+func parseRelation(rel string) (string, string, string, error) {
+	s := strings.TrimSpace(rel)
+	if s == "" {
+		return "", "", "", fmt.Errorf("relation is empty")
+	}
+	// deb syntax, but keep rpm names like perl(Foo) as is
+	if n := strings.LastIndexByte(s, '('); n != -1 && strings.HasSuffix(s, ")") {
+		if inner := strings.TrimSpace(s[n+1 : len(s)-1]); strings.IndexAny(inner, "<>=") == 0 {
+			s = s[:n] + " " + inner
+		}
+	}
+	n := strings.IndexAny(s, "<>=")
+	if n == -1 {
+		if strings.ContainsAny(s, " \t") {
+			return "", "", "", fmt.Errorf("relation is invalid: %s", rel)
+		}
+		return s, "", "", nil
+	}
+	name := strings.TrimSpace(s[:n])
+	s = s[n:]
+	var op string
+	for _, o := range relationOps {
+		if strings.HasPrefix(s, o) {
+			op = o
+			break
+		}
+	}
+	version := strings.TrimSpace(s[len(op):])
+	if name == "" || version == "" || strings.ContainsAny(name, " \t") || strings.ContainsAny(version, " \t<>=") {
+		return "", "", "", fmt.Errorf("relation is invalid: %s", rel)
+	}
+	return name, op, version, nil
+}
+
+// formatRelation translate relation from fpm syntax to output package syntax
+func formatRelation(rel string, outputType OutputType) (string, error) {
+	if strings.Contains(rel, "|") {
+		if outputType != DEB {
+			return "", fmt.Errorf("relation alternatives supported only for deb: %s", rel)
+		}
+		alts := strings.Split(rel, "|")
+		for i := range alts {
+			alt, err := formatRelation(alts[i], outputType)
+			if err != nil {
+				return "", err
+			}
+			alts[i] = alt
+		}
+		return strings.Join(alts, " | "), nil
+	}
+
+	name, op, version, err := parseRelation(rel)
+	if err != nil {
+		return "", err
+	}
+	if op == "" {
+		return name, nil
+	}
+
+	switch outputType {
+	case DEB:
+		switch op {
+		case "==":
+			op = "="
+		case ">":
+			op = ">>"
+		case "<":
+			op = "<<"
+		}
+		return name + " (" + op + " " + version + ")", nil
+	case RPM:
+		switch op {
+		case "==":
+			op = "="
+		case ">>":
+			op = ">"
+		case "<<":
+			op = "<"
+		}
+		return name + " " + op + " " + version, nil
+	case APK:
+		switch op {
+		case "==":
+			op = "="
+		case ">>":
+			op = ">"
+		case "<<":
+			op = "<"
+		}
+		return name + op + version, nil
+	default:
+		return "", fmt.Errorf("relations not supported for %s", outputType.String())
+	}
+}
+
+func formatRelations(rels StringSlice, outputType OutputType) ([]string, error) {
+	if len(rels) == 0 {
+		return nil, nil
+	}
+	formatted := make([]string, 0, len(rels))
+	for _, rel := range rels {
+		s, err := formatRelation(rel, outputType)
+		if err != nil {
+			return nil, err
+		}
+		formatted = append(formatted, s)
+	}
+	return formatted, nil
+}
+
+func (p *Packager) SetDepends(rels StringSlice) error {
+	formatted, err := formatRelations(rels, p.OutputType)
+	p.Info.Depends = append(p.Info.Depends, formatted...)
+	return err
+}
+
+func (p *Packager) SetProvides(rels StringSlice) error {
+	formatted, err := formatRelations(rels, p.OutputType)
+	p.Info.Provides = append(p.Info.Provides, formatted...)
+	return err
+}
+
+func (p *Packager) SetConflicts(rels StringSlice) error {
+	formatted, err := formatRelations(rels, p.OutputType)
+	p.Info.Conflicts = append(p.Info.Conflicts, formatted...)
+	return err
+}
+
+func (p *Packager) SetReplaces(rels StringSlice) error {
+	formatted, err := formatRelations(rels, p.OutputType)
+	p.Info.Replaces = append(p.Info.Replaces, formatted...)
+	return err
+}
+
+// debRelations format Debian-only relationship field, other output types is rejected
+func (p *Packager) debRelations(field string, rels StringSlice) ([]string, error) {
+	if len(rels) == 0 {
+		return nil, nil
+	}
+	if p.OutputType != DEB {
+		return nil, fmt.Errorf("%s relation supported only for deb, not for %s", field, p.OutputType.String())
+	}
+	return formatRelations(rels, p.OutputType)
+}
+
+func (p *Packager) SetDebRecommends(rels StringSlice) error {
+	formatted, err := p.debRelations("Recommends", rels)
+	p.Info.Recommends = append(p.Info.Recommends, formatted...)
+	return err
+}
+
+func (p *Packager) SetDebSuggests(rels StringSlice) error {
+	formatted, err := p.debRelations("Suggests", rels)
+	p.Info.Suggests = append(p.Info.Suggests, formatted...)
+	return err
+}
+
+func (p *Packager) SetDebPreDepends(rels StringSlice) error {
+	formatted, err := p.debRelations("Pre-Depends", rels)
+	p.Info.Deb.Predepends = append(p.Info.Deb.Predepends, formatted...)
+	return err
+}
+
+func (p *Packager) SetDebBreaks(rels StringSlice) error {
+	formatted, err := p.debRelations("Breaks", rels)
+	p.Info.Deb.Breaks = append(p.Info.Deb.Breaks, formatted...)
+	return err
+}
+
+// SetDebEnhances set Enhances control field (nfpm has no dedicated field for it)
+func (p *Packager) SetDebEnhances(rels StringSlice) error {
+	enhances, err := p.debRelations("Enhances", rels)
+	if err != nil || len(enhances) == 0 {
+		return err
+	}
+	if p.Info.Deb.Fields == nil {
+		p.Info.Deb.Fields = make(map[string]string)
+	}
+	if s := p.Info.Deb.Fields["Enhances"]; s != "" {
+		enhances = append([]string{s}, enhances...)
+	}
+	p.Info.Deb.Fields["Enhances"] = strings.Join(enhances, ", ")
+	return nil
+}
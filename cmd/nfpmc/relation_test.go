@@ -0,0 +1,100 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_formatRelation(t *testing.T) {
+	tests := []struct {
+		rel     string
+		deb     string
+		rpm     string
+		apk     string
+		wantErr bool
+	}{
+		{rel: "libc6", deb: "libc6", rpm: "libc6", apk: "libc6"},
+		{rel: "libc6 >= 2.28", deb: "libc6 (>= 2.28)", rpm: "libc6 >= 2.28", apk: "libc6>=2.28"},
+		{rel: "libc6>=2.28", deb: "libc6 (>= 2.28)", rpm: "libc6 >= 2.28", apk: "libc6>=2.28"},
+		{rel: "libc6 (>= 2.28)", deb: "libc6 (>= 2.28)", rpm: "libc6 >= 2.28", apk: "libc6>=2.28"},
+		{rel: "bash > 4", deb: "bash (>> 4)", rpm: "bash > 4", apk: "bash>4"},
+		{rel: "bash << 5", deb: "bash (<< 5)", rpm: "bash < 5", apk: "bash<5"},
+		{rel: "bash == 5.0-1", deb: "bash (= 5.0-1)", rpm: "bash = 5.0-1", apk: "bash=5.0-1"},
+		{rel: "perl(Foo) >= 1.0", deb: "perl(Foo) (>= 1.0)", rpm: "perl(Foo) >= 1.0", apk: "perl(Foo)>=1.0"},
+		{rel: "", wantErr: true},
+		{rel: "libc6 >=", wantErr: true},
+		{rel: "libc6 2.28", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			for outputType, want := range map[OutputType]string{DEB: tt.deb, RPM: tt.rpm, APK: tt.apk} {
+				got, err := formatRelation(tt.rel, outputType)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("formatRelation(%q, %s) error = %v, wantErr %v", tt.rel, outputType.String(), err, tt.wantErr)
+					continue
+				}
+				if got != want {
+					t.Errorf("formatRelation(%q, %s) = %q, want %q", tt.rel, outputType.String(), got, want)
+				}
+			}
+		})
+	}
+}
+
+func Test_formatRelationAlternatives(t *testing.T) {
+	got, err := formatRelation("default-mta | mail-transport-agent >= 1", DEB)
+	if err != nil {
+		t.Fatalf("formatRelation() error = %v", err)
+	}
+	if want := "default-mta | mail-transport-agent (>= 1)"; got != want {
+		t.Errorf("formatRelation() = %q, want %q", got, want)
+	}
+	if _, err = formatRelation("default-mta | postfix", RPM); err == nil {
+		t.Errorf("formatRelation() for rpm alternatives must failed")
+	}
+}
+
+func TestPackager_SetRelations(t *testing.T) {
+	// relations imported from input package are kept
+	p := Packager{OutputType: RPM}
+	p.Info.Depends = []string{"glibc"}
+	if err := p.SetDepends(StringSlice{"bash >= 4"}); err != nil {
+		t.Fatalf("Packager.SetDepends() error = %v", err)
+	}
+	if want := []string{"glibc", "bash >= 4"}; !reflect.DeepEqual(p.Info.Depends, want) {
+		t.Errorf("Packager.SetDepends() = %q, want %q", p.Info.Depends, want)
+	}
+}
+
+func TestPackager_SetDebRelations(t *testing.T) {
+	p := Packager{OutputType: DEB}
+	if err := p.SetDebPreDepends(StringSlice{"dpkg >= 1.17"}); err != nil {
+		t.Fatalf("Packager.SetDebPreDepends() error = %v", err)
+	}
+	if err := p.SetDebEnhances(StringSlice{"foo", "bar > 1"}); err != nil {
+		t.Fatalf("Packager.SetDebEnhances() error = %v", err)
+	}
+	// relations are appended
+	if err := p.SetDebPreDepends(StringSlice{"libc6"}); err != nil {
+		t.Fatalf("Packager.SetDebPreDepends() error = %v", err)
+	}
+	if err := p.SetDebEnhances(StringSlice{"baz"}); err != nil {
+		t.Fatalf("Packager.SetDebEnhances() error = %v", err)
+	}
+	if want := []string{"dpkg (>= 1.17)", "libc6"}; !reflect.DeepEqual(p.Info.Deb.Predepends, want) {
+		t.Errorf("Packager.SetDebPreDepends() = %q, want %q", p.Info.Deb.Predepends, want)
+	}
+	if want := "foo, bar (>> 1), baz"; p.Info.Deb.Fields["Enhances"] != want {
+		t.Errorf("Packager.SetDebEnhances() = %q, want %q", p.Info.Deb.Fields["Enhances"], want)
+	}
+
+	for _, outputType := range []OutputType{RPM, APK} {
+		p := Packager{OutputType: outputType}
+		if err := p.SetDebBreaks(StringSlice{"foo"}); err == nil {
+			t.Errorf("Packager.SetDebBreaks() for %s must failed", outputType.String())
+		}
+		if err := p.SetDebRecommends(nil); err != nil {
+			t.Errorf("Packager.SetDebRecommends() for %s with empty list error = %v", outputType.String(), err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/goreleaser/nfpm/v2/files"
+)
+
+// reproducibleUmask is applied to modes read from disk (group and other write bits depends on checkout umask)
+const reproducibleUmask = 0o022
+
+// sourceDateEpoch return time from SOURCE_DATE_EPOCH environment variable, unix epoch if not set
+func sourceDateEpoch() (time.Time, error) {
+	s := os.Getenv("SOURCE_DATE_EPOCH")
+	if s == "" {
+		return time.Unix(0, 0).UTC(), nil
+	}
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || sec < 0 {
+		return time.Time{}, fmt.Errorf("SOURCE_DATE_EPOCH is invalid: %s", s)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// normalize prepare contents for reproducible build: package time is set to SOURCE_DATE_EPOCH,
+// files mtimes are clamped to it, owner and group are set explicitly and contents are sorted by destination
+func (p *Packager) normalize() error {
+	mtime, err := sourceDateEpoch()
+	if err != nil {
+		return err
+	}
+	p.Info.MTime = mtime
+	p.Info.Umask = reproducibleUmask
+
+	for _, c := range p.Info.Contents {
+		if c.FileInfo == nil {
+			c.FileInfo = &files.ContentFileInfo{}
+		}
+		if c.FileInfo.Owner == "" {
+			c.FileInfo.Owner = "root"
+		}
+		if c.FileInfo.Group == "" {
+			c.FileInfo.Group = "root"
+		}
+		c.FileInfo.MTime = mtime
+		if c.Source == "" || c.Type == symlinkStr || c.Type == dirStr || c.Type == ghostStr {
+			continue
+		}
+		st, err := os.Stat(c.Source)
+		if err != nil {
+			return err
+		}
+		if st.ModTime().Before(mtime) {
+			c.FileInfo.MTime = st.ModTime().Truncate(time.Second).UTC()
+		}
+	}
+
+	sort.Stable(p.Info.Contents)
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_sourceDateEpoch(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	mtime, err := sourceDateEpoch()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), mtime.Unix())
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1600000000")
+	mtime, err = sourceDateEpoch()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1600000000), mtime.Unix())
+
+	for _, s := range []string{"-1", "now", "1.5"} {
+		t.Setenv("SOURCE_DATE_EPOCH", s)
+		_, err = sourceDateEpoch()
+		assert.Errorf(t, err, "SOURCE_DATE_EPOCH=%s", s)
+	}
+}
+
+func fileHash(t *testing.T, name string) string {
+	f, err := os.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	require.NoError(t, err)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestPackage_Reproducible(t *testing.T) {
+	srcDir := t.TempDir()
+	for name, data := range map[string]string{
+		"bin/test":         "#!/bin/sh\n",
+		"conf/test.conf":   "key = value\n",
+		"doc/README":       "readme\n",
+		"doc/sub/CHANGES":  "changes\n",
+		"share/empty/.dir": "",
+	} {
+		name = path.Join(srcDir, name)
+		require.NoError(t, os.MkdirAll(path.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(data), 0644))
+	}
+
+	chmod := func(modes map[string]os.FileMode) {
+		for name, mode := range modes {
+			require.NoError(t, os.Chmod(path.Join(srcDir, name), mode))
+		}
+	}
+	build := func(outputType OutputType, outDir string, mappings []string) string {
+		p := Packager{OutputType: outputType, OutDir: outDir, Dir: srcDir, Reproducible: true}
+		p.Info.Name = "test"
+		p.Info.Version = "1.0.0"
+		p.Info.Release = "1"
+		p.Info.Arch = "amd64"
+
+		require.NoError(t, p.Init())
+		require.NoError(t, p.AddFiles(mappings))
+		require.NoError(t, p.AddSymlinks([]string{"/usr/bin/test=/usr/bin/test-link"}))
+		require.NoError(t, p.SetTypeRules(nil, false, false))
+		require.NoError(t, p.Validate())
+		target, err := p.Do(false)
+		require.NoError(t, err)
+		return target
+	}
+
+	for _, epoch := range []string{"1600000000", ""} {
+		t.Setenv("SOURCE_DATE_EPOCH", epoch)
+		for _, outputType := range []OutputType{RPM, DEB, APK} {
+			t.Run(outputType.String()+"#"+epoch, func(t *testing.T) {
+				chmod(map[string]os.FileMode{"bin/test": 0755, "conf/test.conf": 0644, "doc/README": 0644})
+				first := build(outputType, t.TempDir(), []string{"bin/test=/usr/bin/", "conf/=/etc/test/", "doc/=/usr/share/doc/test/"})
+
+				// files touched, checkout with other umask and mappings in other order
+				now := time.Now()
+				for _, name := range []string{"bin/test", "conf/test.conf", "doc/README"} {
+					require.NoError(t, os.Chtimes(path.Join(srcDir, name), now, now))
+				}
+				chmod(map[string]os.FileMode{"bin/test": 0775, "conf/test.conf": 0664, "doc/README": 0664})
+				second := build(outputType, t.TempDir(), []string{"doc/=/usr/share/doc/test/", "conf/=/etc/test/", "bin/test=/usr/bin/"})
+
+				assert.Equal(t, fileHash(t, first), fileHash(t, second), "packages differ")
+			})
+		}
+	}
+}
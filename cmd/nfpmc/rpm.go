@@ -0,0 +1,812 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cavaliergopher/cpio"
+	"github.com/goreleaser/nfpm/v2/files"
+)
+
+// rpm header tags
+const (
+	rpmTagName             = 1000
+	rpmTagVersion          = 1001
+	rpmTagRelease          = 1002
+	rpmTagEpoch            = 1003
+	rpmTagSummary          = 1004
+	rpmTagDescription      = 1005
+	rpmTagVendor           = 1011
+	rpmTagLicense          = 1014
+	rpmTagPackager         = 1015
+	rpmTagGroup            = 1016
+	rpmTagURL              = 1020
+	rpmTagArch             = 1022
+	rpmTagPrein            = 1023
+	rpmTagPostin           = 1024
+	rpmTagPreun            = 1025
+	rpmTagPostun           = 1026
+	rpmTagOldFilenames     = 1027
+	rpmTagFileModes        = 1030
+	rpmTagFileMTimes       = 1034
+	rpmTagFileLinkTos      = 1036
+	rpmTagFileFlags        = 1037
+	rpmTagFileUserName     = 1039
+	rpmTagFileGroupName    = 1040
+	rpmTagProvideName      = 1047
+	rpmTagRequireFlags     = 1048
+	rpmTagRequireName      = 1049
+	rpmTagRequireVersion   = 1050
+	rpmTagConflictFlags    = 1053
+	rpmTagConflictName     = 1054
+	rpmTagConflictVersion  = 1055
+	rpmTagTriggerScripts   = 1065
+	rpmTagVerifyScript     = 1079
+	rpmTagChangelogTime    = 1080
+	rpmTagPreinProg        = 1085
+	rpmTagPostinProg       = 1086
+	rpmTagPreunProg        = 1087
+	rpmTagPostunProg       = 1088
+	rpmTagObsoleteName     = 1090
+	rpmTagVerifyScriptProg = 1091
+	rpmTagPrefixes         = 1098
+	rpmTagProvideFlags     = 1112
+	rpmTagProvideVersion   = 1113
+	rpmTagObsoleteFlags    = 1114
+	rpmTagObsoleteVersion  = 1115
+	rpmTagDirIndexes       = 1116
+	rpmTagBaseNames        = 1117
+	rpmTagDirNames         = 1118
+	rpmTagPayloadFormat    = 1124
+	rpmTagPretrans         = 1151
+	rpmTagPosttrans        = 1152
+	rpmTagPretransProg     = 1153
+	rpmTagPosttransProg    = 1154
+	rpmTagRecommendName    = 5046
+	rpmTagRecommendVersion = 5047
+	rpmTagRecommendFlags   = 5048
+	rpmTagSuggestName      = 5049
+	rpmTagSuggestVersion   = 5050
+	rpmTagSuggestFlags     = 5051
+)
+
+// rpm header entry types
+const (
+	rpmTypeInt8        = 2
+	rpmTypeInt16       = 3
+	rpmTypeInt32       = 4
+	rpmTypeInt64       = 5
+	rpmTypeString      = 6
+	rpmTypeStringArray = 8
+	rpmTypeI18NString  = 9
+)
+
+// rpm file flags
+const (
+	rpmFileConfig    = 1 << 0
+	rpmFileDoc       = 1 << 1
+	rpmFileNoReplace = 1 << 4
+	rpmFileGhost     = 1 << 6
+	rpmFileLicense   = 1 << 7
+	rpmFileReadme    = 1 << 8
+)
+
+// rpm dependency flags
+const (
+	rpmSenseLess    = 1 << 1
+	rpmSenseGreater = 1 << 2
+	rpmSenseEqual   = 1 << 3
+	rpmSenseRPMLib  = 1 << 24
+)
+
+const (
+	rpmLeadSize = 96
+	// rpmIndexMax limit header index entries count and data size (as rpm does)
+	rpmIndexMax = 0x10000
+	rpmDataMax  = 256 * 1024 * 1024
+)
+
+var (
+	rpmLeadMagic   = []byte{0xed, 0xab, 0xee, 0xdb}
+	rpmHeaderMagic = []byte{0x8e, 0xad, 0xe8, 0x01}
+)
+
+// rpmArchToGo map rpm architectures to nfpm (Go) architecture names, other names are the same
+var rpmArchToGo = map[string]string{
+	"noarch":   "all",
+	"x86_64":   "amd64",
+	"i386":     "386",
+	"i486":     "386",
+	"i586":     "386",
+	"i686":     "386",
+	"aarch64":  "arm64",
+	"armv5tel": "arm5",
+	"armv6hl":  "arm6",
+	"armv7hl":  "arm7",
+	"armv7l":   "arm7",
+	"mips64el": "mips64le",
+	"mipsel":   "mipsle",
+}
+
+type rpmHeaderEntry struct {
+	typ   uint32
+	count uint32
+	data  []byte
+}
+
+// rpmHeader is a rpm header index entries by tag
+type rpmHeader map[uint32]rpmHeaderEntry
+
+// readRPMHeader read rpm header structure, pad is set for signature header (padded to 8 bytes)
+func readRPMHeader(r io.Reader, pad bool) (rpmHeader, error) {
+	var intro [16]byte
+	if _, err := io.ReadFull(r, intro[:]); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(intro[:4], rpmHeaderMagic) {
+		return nil, fmt.Errorf("header magic is invalid")
+	}
+	nindex := binary.BigEndian.Uint32(intro[8:])
+	hsize := binary.BigEndian.Uint32(intro[12:])
+	if nindex > rpmIndexMax || hsize > rpmDataMax {
+		return nil, fmt.Errorf("header is too large")
+	}
+	index := make([]byte, nindex*16)
+	if _, err := io.ReadFull(r, index); err != nil {
+		return nil, err
+	}
+	size := hsize
+	if pad && size%8 != 0 {
+		size += 8 - size%8
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	data = data[:hsize]
+
+	h := make(rpmHeader, nindex)
+	for i := uint32(0); i < nindex; i++ {
+		e := index[i*16 : i*16+16]
+		tag := binary.BigEndian.Uint32(e)
+		offset := binary.BigEndian.Uint32(e[8:])
+		if offset > hsize {
+			return nil, fmt.Errorf("header tag %d offset is invalid", tag)
+		}
+		h[tag] = rpmHeaderEntry{
+			typ:   binary.BigEndian.Uint32(e[4:]),
+			count: binary.BigEndian.Uint32(e[12:]),
+			data:  data[offset:],
+		}
+	}
+	return h, nil
+}
+
+// strings return string values for tag (for i18n strings only first is used)
+func (h rpmHeader) strings(tag uint32) ([]string, error) {
+	e, ok := h[tag]
+	if !ok {
+		return nil, nil
+	}
+	count := e.count
+	switch e.typ {
+	case rpmTypeString, rpmTypeI18NString:
+		count = 1
+	case rpmTypeStringArray:
+	default:
+		return nil, fmt.Errorf("header tag %d is not a string", tag)
+	}
+	values := make([]string, 0, count)
+	data := e.data
+	for i := uint32(0); i < count; i++ {
+		n := bytes.IndexByte(data, 0)
+		if n == -1 {
+			return nil, fmt.Errorf("header tag %d is truncated", tag)
+		}
+		values = append(values, string(data[:n]))
+		data = data[n+1:]
+	}
+	return values, nil
+}
+
+// string return first string value for tag
+func (h rpmHeader) string(tag uint32) (string, error) {
+	values, err := h.strings(tag)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+	return values[0], nil
+}
+
+// ints return integer values for tag
+func (h rpmHeader) ints(tag uint32) ([]int64, error) {
+	e, ok := h[tag]
+	if !ok {
+		return nil, nil
+	}
+	var size uint32
+	switch e.typ {
+	case rpmTypeInt8:
+		size = 1
+	case rpmTypeInt16:
+		size = 2
+	case rpmTypeInt32:
+		size = 4
+	case rpmTypeInt64:
+		size = 8
+	default:
+		return nil, fmt.Errorf("header tag %d is not an integer", tag)
+	}
+	if uint64(len(e.data)) < uint64(e.count)*uint64(size) {
+		return nil, fmt.Errorf("header tag %d is truncated", tag)
+	}
+	values := make([]int64, e.count)
+	for i := range values {
+		b := e.data[uint32(i)*size:]
+		switch size {
+		case 1:
+			values[i] = int64(b[0])
+		case 2:
+			values[i] = int64(binary.BigEndian.Uint16(b))
+		case 4:
+			values[i] = int64(binary.BigEndian.Uint32(b))
+		default:
+			values[i] = int64(binary.BigEndian.Uint64(b))
+		}
+	}
+	return values, nil
+}
+
+// rpmFile is a file attributes from rpm header
+type rpmFile struct {
+	Name   string
+	Mode   int64
+	Flags  int64
+	Owner  string
+	Group  string
+	MTime  time.Time
+	LinkTo string
+}
+
+// readRPMFiles read file list from rpm header
+func readRPMFiles(h rpmHeader) ([]*rpmFile, error) {
+	names, err := h.strings(rpmTagOldFilenames)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		baseNames, err := h.strings(rpmTagBaseNames)
+		if err != nil {
+			return nil, err
+		}
+		dirNames, err := h.strings(rpmTagDirNames)
+		if err != nil {
+			return nil, err
+		}
+		dirIndexes, err := h.ints(rpmTagDirIndexes)
+		if err != nil {
+			return nil, err
+		}
+		if len(dirIndexes) != len(baseNames) {
+			return nil, fmt.Errorf("header file names are invalid")
+		}
+		names = make([]string, len(baseNames))
+		for i, name := range baseNames {
+			if dirIndexes[i] >= int64(len(dirNames)) {
+				return nil, fmt.Errorf("header file names are invalid")
+			}
+			names[i] = dirNames[dirIndexes[i]] + name
+		}
+	}
+
+	modes, err := h.ints(rpmTagFileModes)
+	if err != nil {
+		return nil, err
+	}
+	mtimes, err := h.ints(rpmTagFileMTimes)
+	if err != nil {
+		return nil, err
+	}
+	flags, err := h.ints(rpmTagFileFlags)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := h.strings(rpmTagFileUserName)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := h.strings(rpmTagFileGroupName)
+	if err != nil {
+		return nil, err
+	}
+	linkTos, err := h.strings(rpmTagFileLinkTos)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range []int{len(modes), len(mtimes), len(flags), len(owners), len(groups), len(linkTos)} {
+		if n != len(names) {
+			return nil, fmt.Errorf("header file attributes are invalid")
+		}
+	}
+
+	rpmFiles := make([]*rpmFile, len(names))
+	for i, name := range names {
+		rpmFiles[i] = &rpmFile{
+			Name:   cleanEntryName(name),
+			Mode:   modes[i],
+			Flags:  flags[i],
+			Owner:  owners[i],
+			Group:  groups[i],
+			MTime:  time.Unix(mtimes[i], 0),
+			LinkTo: linkTos[i],
+		}
+	}
+	return rpmFiles, nil
+}
+
+// rpmRelations read relations from rpm header in fpm syntax, rpmlib requirements are skipped
+func rpmRelations(h rpmHeader, nameTag, versionTag, flagsTag uint32) ([]string, error) {
+	names, err := h.strings(nameTag)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := h.strings(versionTag)
+	if err != nil {
+		return nil, err
+	}
+	flags, err := h.ints(flagsTag)
+	if err != nil {
+		return nil, err
+	}
+	rels := make([]string, 0, len(names))
+	for i, name := range names {
+		var (
+			flag    int64
+			version string
+		)
+		if i < len(flags) {
+			flag = flags[i]
+		}
+		if i < len(versions) {
+			version = versions[i]
+		}
+		if flag&rpmSenseRPMLib != 0 || strings.HasPrefix(name, "rpmlib(") {
+			continue
+		}
+		var op string
+		switch flag & (rpmSenseLess | rpmSenseGreater | rpmSenseEqual) {
+		case rpmSenseLess:
+			op = "<"
+		case rpmSenseGreater:
+			op = ">"
+		case rpmSenseEqual:
+			op = "="
+		case rpmSenseLess | rpmSenseEqual:
+			op = "<="
+		case rpmSenseGreater | rpmSenseEqual:
+			op = ">="
+		}
+		if op == "" || version == "" {
+			rels = append(rels, name)
+		} else {
+			rels = append(rels, name+" "+op+" "+version)
+		}
+	}
+	return rels, nil
+}
+
+// importRPMRelations translate rpm relations to output package syntax, rich dependencies and rpm specific names
+// (like perl(Foo), libc.so.6()(64bit) or /bin/sh) are reported as untranslated for other packages
+func (p *Packager) importRPMRelations(field string, rels []string) ([]string, error) {
+	translated := make([]string, 0, len(rels))
+	for _, rel := range rels {
+		if strings.HasPrefix(rel, "(") {
+			p.untranslated("rpm %s %s is not supported (rich dependency)", field, rel)
+			continue
+		}
+		f := strings.Fields(rel)
+		if len(f) == 0 {
+			p.untranslated("rpm %s with empty name is skipped", field)
+			continue
+		}
+		if p.OutputType != RPM && strings.ContainsAny(f[0], "()/") {
+			p.untranslated("rpm %s %s is not supported for %s", field, rel, p.OutputType.String())
+			continue
+		}
+		s, err := formatRelation(rel, p.OutputType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		translated = append(translated, s)
+	}
+	return translated, nil
+}
+
+// importRPMFields import rpm header tags to package info, fields from command line are not overwritten
+func (p *Packager) importRPMFields(h rpmHeader) error {
+	output := p.OutputType.String()
+
+	var err error
+	fields := []struct {
+		tag   uint32
+		value *string
+	}{
+		{tag: rpmTagName, value: &p.Info.Name},
+		{tag: rpmTagVendor, value: &p.Info.Vendor},
+		{tag: rpmTagLicense, value: &p.Info.License},
+		{tag: rpmTagPackager, value: &p.Info.Maintainer},
+		{tag: rpmTagURL, value: &p.Info.Homepage},
+	}
+	for _, f := range fields {
+		value, err := h.string(f.tag)
+		if err != nil {
+			return err
+		}
+		setIfEmpty(f.value, value)
+	}
+
+	if p.Info.Version == "" {
+		if p.Info.Version, err = h.string(rpmTagVersion); err != nil {
+			return err
+		}
+		release, err := h.string(rpmTagRelease)
+		if err != nil {
+			return err
+		}
+		setIfEmpty(&p.Info.Release, release)
+		epoch, err := h.ints(rpmTagEpoch)
+		if err != nil {
+			return err
+		}
+		if len(epoch) > 0 && epoch[0] != 0 {
+			setIfEmpty(&p.Info.Epoch, strconv.FormatInt(epoch[0], 10))
+		}
+	}
+
+	arch, err := h.string(rpmTagArch)
+	if err != nil {
+		return err
+	}
+	if goArch, ok := rpmArchToGo[arch]; ok {
+		arch = goArch
+	}
+	setIfEmpty(&p.Info.Arch, arch)
+
+	summary, err := h.string(rpmTagSummary)
+	if err != nil {
+		return err
+	}
+	description, err := h.string(rpmTagDescription)
+	if err != nil {
+		return err
+	}
+	if p.Info.Description == "" {
+		// nfpm use first description line as summary
+		if summary != "" && summary != strings.SplitN(description, "\n", 2)[0] {
+			if p.OutputType == RPM {
+				setIfEmpty(&p.Info.RPM.Summary, summary)
+			} else if description == "" {
+				description = summary
+			} else {
+				description = summary + "\n" + description
+			}
+		}
+		p.Info.Description = description
+	}
+
+	group, err := h.string(rpmTagGroup)
+	if err != nil {
+		return err
+	}
+	if group != "" && group != "Unspecified" {
+		if p.OutputType == RPM {
+			setIfEmpty(&p.Info.RPM.Group, group)
+		} else {
+			p.untranslated("rpm tag Group is not supported for %s", output)
+		}
+	}
+
+	prefixes, err := h.strings(rpmTagPrefixes)
+	if err != nil {
+		return err
+	}
+	if len(prefixes) > 0 {
+		if p.OutputType == RPM {
+			p.Info.RPM.Prefixes = append(p.Info.RPM.Prefixes, prefixes...)
+		} else {
+			p.untranslated("rpm tag Prefixes is not supported for %s", output)
+		}
+	}
+
+	relations := []struct {
+		field                     string
+		nameTag, versionTag, flag uint32
+		rels                      *[]string
+	}{
+		{field: "Requires", nameTag: rpmTagRequireName, versionTag: rpmTagRequireVersion, flag: rpmTagRequireFlags, rels: &p.Info.Depends},
+		{field: "Provides", nameTag: rpmTagProvideName, versionTag: rpmTagProvideVersion, flag: rpmTagProvideFlags, rels: &p.Info.Provides},
+		{field: "Conflicts", nameTag: rpmTagConflictName, versionTag: rpmTagConflictVersion, flag: rpmTagConflictFlags, rels: &p.Info.Conflicts},
+		{field: "Obsoletes", nameTag: rpmTagObsoleteName, versionTag: rpmTagObsoleteVersion, flag: rpmTagObsoleteFlags, rels: &p.Info.Replaces},
+		{field: "Recommends", nameTag: rpmTagRecommendName, versionTag: rpmTagRecommendVersion, flag: rpmTagRecommendFlags, rels: &p.Info.Recommends},
+		{field: "Suggests", nameTag: rpmTagSuggestName, versionTag: rpmTagSuggestVersion, flag: rpmTagSuggestFlags, rels: &p.Info.Suggests},
+	}
+	name, _ := h.string(rpmTagName)
+	for _, r := range relations {
+		rels, err := rpmRelations(h, r.nameTag, r.versionTag, r.flag)
+		if err != nil {
+			return err
+		}
+		if r.field == "Provides" {
+			// package provides itself (added by rpm packager)
+			filtered := rels[:0]
+			for _, rel := range rels {
+				// empty names are reported on import
+				if f := strings.Fields(rel); len(f) == 0 || (f[0] != name && !strings.HasPrefix(f[0], name+"(")) {
+					filtered = append(filtered, rel)
+				}
+			}
+			rels = filtered
+		}
+		if len(rels) == 0 {
+			continue
+		}
+		if p.OutputType == APK && (r.field == "Recommends" || r.field == "Suggests") {
+			p.untranslated("rpm tag %s is not supported for %s", r.field, output)
+			continue
+		}
+		if rels, err = p.importRPMRelations(r.field, rels); err != nil {
+			return err
+		}
+		*r.rels = append(*r.rels, rels...)
+	}
+
+	for _, t := range []struct {
+		tag  uint32
+		name string
+	}{
+		{tag: rpmTagTriggerScripts, name: "triggers"},
+		{tag: rpmTagChangelogTime, name: "changelog"},
+	} {
+		if _, ok := h[t.tag]; ok {
+			p.untranslated("rpm %s are not supported", t.name)
+		}
+	}
+	return nil
+}
+
+// importRPMScripts stage rpm scriptlets, scripts from command line are not overwritten
+func (p *Packager) importRPMScripts(h rpmHeader) error {
+	scripts := []struct {
+		name         string
+		tag, progTag uint32
+		script       *string
+		rpmSpecific  bool
+	}{
+		{name: "%pretrans", tag: rpmTagPretrans, progTag: rpmTagPretransProg, script: &p.Info.RPM.Scripts.PreTrans, rpmSpecific: true},
+		{name: "%pre", tag: rpmTagPrein, progTag: rpmTagPreinProg, script: &p.Info.Scripts.PreInstall},
+		{name: "%post", tag: rpmTagPostin, progTag: rpmTagPostinProg, script: &p.Info.Scripts.PostInstall},
+		{name: "%preun", tag: rpmTagPreun, progTag: rpmTagPreunProg, script: &p.Info.Scripts.PreRemove},
+		{name: "%postun", tag: rpmTagPostun, progTag: rpmTagPostunProg, script: &p.Info.Scripts.PostRemove},
+		{name: "%posttrans", tag: rpmTagPosttrans, progTag: rpmTagPosttransProg, script: &p.Info.RPM.Scripts.PostTrans, rpmSpecific: true},
+		{name: "%verifyscript", tag: rpmTagVerifyScript, progTag: rpmTagVerifyScriptProg, script: &p.Info.RPM.Scripts.Verify, rpmSpecific: true},
+	}
+	var maintScripts bool
+	for _, s := range scripts {
+		script, err := h.string(s.tag)
+		if err != nil {
+			return err
+		}
+		prog, err := h.string(s.progTag)
+		if err != nil {
+			return err
+		}
+		if script == "" && prog == "" {
+			continue
+		}
+		if s.rpmSpecific && p.OutputType != RPM {
+			p.untranslated("rpm scriptlet %s is not supported for %s", s.name, p.OutputType.String())
+			continue
+		}
+		if prog != "" && prog != "/bin/sh" {
+			p.untranslated("rpm scriptlet %s with interpreter %s is not supported", s.name, prog)
+			continue
+		}
+		if *s.script != "" {
+			// set from command line
+			continue
+		}
+		if !strings.HasPrefix(script, "#!") {
+			script = "#!/bin/sh\n" + script
+		}
+		if *s.script, err = p.stage(strings.NewReader(script), time.Time{}); err != nil {
+			return err
+		}
+		maintScripts = true
+	}
+	if maintScripts && p.OutputType != RPM {
+		p.untranslated("rpm scriptlets are imported as is, check them for rpm specific arguments (number of installed package instances)")
+	}
+	return nil
+}
+
+// rpmContentType return content type for rpm file flags
+func (p *Packager) rpmContentType(flags int64) string {
+	switch {
+	case flags&rpmFileGhost != 0:
+		return ghostStr
+	case flags&rpmFileConfig != 0 && flags&rpmFileNoReplace != 0:
+		return configStr
+	case flags&rpmFileConfig != 0:
+		return configReplaceStr
+	case flags&rpmFileDoc != 0:
+		return p.contentType(docStr)
+	case flags&rpmFileLicense != 0:
+		return p.contentType(licenseStr)
+	case flags&rpmFileReadme != 0:
+		return p.contentType(readmeStr)
+	default:
+		return defaultStr
+	}
+}
+
+// rpmEntry return archive entry for rpm file
+func (p *Packager) rpmEntry(f *rpmFile) *archiveEntry {
+	e := &archiveEntry{
+		Name: f.Name,
+		FileInfo: files.ContentFileInfo{
+			Owner: f.Owner,
+			Group: f.Group,
+			Mode:  os.FileMode(f.Mode & 07777),
+			MTime: f.MTime,
+		},
+	}
+	switch f.Mode & cpio.ModeType {
+	case cpio.TypeDir:
+		e.Type = dirStr
+	case cpio.TypeSymlink:
+		e.Type = symlinkStr
+		e.Target = f.LinkTo
+		e.FileInfo.Mode = 0
+	default:
+		e.Type = p.rpmContentType(f.Flags)
+	}
+	return e
+}
+
+// readRPMPayload add files from rpm cpio payload, file attributes are taken from header
+func readRPMPayload(r io.Reader, rpmFiles []*rpmFile, a *archiveImporter) error {
+	dr, err := decompress(r)
+	if err != nil {
+		return err
+	}
+	defer dr.Close()
+
+	byName := make(map[string]*rpmFile, len(rpmFiles))
+	for _, f := range rpmFiles {
+		byName[f.Name] = f
+	}
+	added := make(map[string]bool, len(rpmFiles))
+	// hardlinks without data by inode, data is stored with last link
+	links := make(map[int64][]*archiveEntry)
+
+	cr := cpio.NewReader(dr)
+	for {
+		hdr, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := cleanEntryName(hdr.Name)
+		f, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("%s: file not found in header", name)
+		}
+		added[name] = true
+		e := a.p.rpmEntry(f)
+		switch {
+		case e.Type == dirStr || e.Type == symlinkStr:
+			err = a.add(e, nil)
+		case e.Type == ghostStr:
+			err = fmt.Errorf("%s: ghost file found in payload", name)
+		case hdr.Mode&cpio.ModeType != cpio.TypeReg:
+			err = fmt.Errorf("%s: unsupported file type %s", name, hdr.Mode.String())
+		case hdr.Links > 1 && hdr.Size == 0:
+			links[hdr.Inode] = append(links[hdr.Inode], e)
+		case hdr.Links > 1:
+			// data is staged for links, even if file is not selected
+			if err = a.addTarget(e, cr); err != nil {
+				break
+			}
+			for _, link := range links[hdr.Inode] {
+				link.Target = name
+				if err = a.addLink(link); err != nil {
+					break
+				}
+			}
+			delete(links, hdr.Inode)
+		default:
+			err = a.add(e, cr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, entries := range links {
+		// all links are empty
+		for _, e := range entries {
+			if err = a.add(e, bytes.NewReader(nil)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range rpmFiles {
+		if added[f.Name] {
+			continue
+		}
+		if f.Flags&rpmFileGhost == 0 {
+			return fmt.Errorf("%s: file not found in payload", f.Name)
+		}
+		if a.p.OutputType != RPM {
+			a.p.untranslated("rpm %%ghost file /%s is not supported for %s", f.Name, a.p.OutputType.String())
+			continue
+		}
+		if err = a.add(a.p.rpmEntry(f), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addRPM add files and metadata from rpm package
+func (p *Packager) addRPM(fm FileMap) error {
+	return p.addArchives(fm, p.readRPM)
+}
+
+func (p *Packager) readRPM(name string, a *archiveImporter) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	lead := make([]byte, rpmLeadSize)
+	if _, err = io.ReadFull(f, lead); err != nil {
+		return err
+	}
+	if !bytes.Equal(lead[:4], rpmLeadMagic) {
+		return fmt.Errorf("not a rpm package")
+	}
+	if _, err = readRPMHeader(f, true); err != nil {
+		return fmt.Errorf("signature: %w", err)
+	}
+	h, err := readRPMHeader(f, false)
+	if err != nil {
+		return fmt.Errorf("header: %w", err)
+	}
+	if format, err := h.string(rpmTagPayloadFormat); err != nil {
+		return err
+	} else if format != "" && format != "cpio" {
+		return fmt.Errorf("payload format %s is not supported", format)
+	}
+
+	rpmFiles, err := readRPMFiles(h)
+	if err != nil {
+		return err
+	}
+	if err = readRPMPayload(f, rpmFiles, a); err != nil {
+		return fmt.Errorf("payload: %w", err)
+	}
+	if err = p.importRPMFields(h); err != nil {
+		return err
+	}
+	return p.importRPMScripts(h)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/cavaliergopher/cpio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildTestRPM build rpm package with nfpmc
+func buildTestRPM(t *testing.T, dir, compression string) string {
+	srcDir := path.Join(dir, "src")
+	for name, data := range map[string]string{
+		"etc/test/test.conf":          "key = value\n",
+		"etc/test/defaults.conf":      "key = default\n",
+		"usr/bin/test":                "#!/bin/sh\n",
+		"usr/share/doc/test/README":   "readme\n",
+		"usr/share/doc/test/NOTES":    "notes\n",
+		"etc/test/plain.env":          "KEY=value\n",
+		"var/lib/test/state":          "",
+		"scripts/postinst":            "#!/bin/sh\necho installed\n",
+		"scripts/pretrans":            "echo pretrans\n",
+		"usr/share/test/data/content": "data\n",
+	} {
+		name = path.Join(srcDir, name)
+		require.NoError(t, os.MkdirAll(path.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(data), 0644))
+	}
+	require.NoError(t, os.Chmod(path.Join(srcDir, "usr/bin/test"), 0755))
+	require.NoError(t, os.Symlink("test", path.Join(srcDir, "usr/bin/test-link")))
+
+	p := Packager{OutputType: RPM, OutDir: dir, Dir: srcDir}
+	p.Info.Name = "test"
+	p.Info.Version = "1.2.3"
+	p.Info.Release = "4"
+	p.Info.Epoch = "1"
+	p.Info.Arch = "amd64"
+	p.Info.Maintainer = "Test <test@example.com>"
+	p.Info.Description = "test package\nlong description"
+	p.Info.Homepage = "https://example.com"
+	p.Info.License = "MIT"
+	p.Info.RPM.Group = "Applications/System"
+	p.Info.RPM.Compression = compression
+	p.Info.Scripts.PostInstall = path.Join(srcDir, "scripts/postinst")
+	p.Info.RPM.Scripts.PreTrans = path.Join(srcDir, "scripts/pretrans")
+
+	require.NoError(t, p.Init())
+	require.NoError(t, p.AddFiles(StringSlice{"etc/=/etc/", "usr/=/usr/", "var/=/var/"}))
+	require.NoError(t, p.SetConfigFiles(StringSlice{"/etc/test/test.conf"}))
+	require.NoError(t, p.SetConfigReplaceFiles(StringSlice{"/etc/test/defaults.conf"}))
+	require.NoError(t, p.SetGhostFiles(StringSlice{"/var/lib/test/state"}))
+	require.NoError(t, p.SetDocFiles(StringSlice{"/usr/share/doc/test/README"}))
+	require.NoError(t, p.SetDepends(StringSlice{"libc6 >= 2.28", "bash"}))
+	require.NoError(t, p.SetProvides(StringSlice{"test-api = 1.2"}))
+	require.NoError(t, p.SetConflicts(StringSlice{"test-old < 1.0"}))
+	require.NoError(t, p.SetReplaces(StringSlice{"test-legacy"}))
+	p.Info.Recommends = []string{"test-doc"}
+	require.NoError(t, p.Validate())
+	target, err := p.Do(false)
+	require.NoError(t, err)
+	return target
+}
+
+func TestPackage_AddFilesRPM(t *testing.T) {
+	for _, compression := range []string{"gzip", "xz", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			dir := t.TempDir()
+			rpm := buildTestRPM(t, dir, compression)
+
+			t.Run("deb", func(t *testing.T) {
+				p := Packager{InputType: INPUT_RPM, OutputType: DEB, OutDir: t.TempDir()}
+				require.NoError(t, p.Init())
+				defer p.Close()
+
+				require.NoError(t, p.AddFiles(StringSlice{rpm}))
+
+				assert.Equal(t, "test", p.Info.Name)
+				assert.Equal(t, "1.2.3", p.Info.Version)
+				assert.Equal(t, "4", p.Info.Release)
+				assert.Equal(t, "1", p.Info.Epoch)
+				assert.Equal(t, "amd64", p.Info.Arch)
+				assert.Equal(t, "Test <test@example.com>", p.Info.Maintainer)
+				assert.Equal(t, "test package\nlong description", p.Info.Description)
+				assert.Equal(t, "https://example.com", p.Info.Homepage)
+				assert.Equal(t, "MIT", p.Info.License)
+				assert.Equal(t, []string{"libc6 (>= 2.28)", "bash"}, p.Info.Depends)
+				assert.Equal(t, []string{"test-api (= 1.2)"}, p.Info.Provides)
+				assert.Equal(t, []string{"test-old (<< 1.0)"}, p.Info.Conflicts)
+				assert.Equal(t, []string{"test-legacy"}, p.Info.Replaces)
+				assert.Equal(t, []string{"test-doc"}, p.Info.Recommends)
+
+				want := map[string]string{
+					"/etc/test/test.conf":          configStr,
+					"/etc/test/defaults.conf":      configReplaceStr,
+					"/usr/bin/test":                defaultStr,
+					"/usr/bin/test-link":           symlinkStr,
+					"/usr/share/doc/test/README":   defaultStr,
+					"/usr/share/test/data/content": defaultStr,
+				}
+				for dest, typ := range want {
+					c, ok := p.FilesMap[dest]
+					if assert.Truef(t, ok, "%s not found", dest) {
+						assert.Equal(t, typ, c.Type, dest)
+					}
+				}
+				assert.Equal(t, "test", p.FilesMap["/usr/bin/test-link"].Source)
+				assert.Equal(t, os.FileMode(0755), p.FilesMap["/usr/bin/test"].FileInfo.Mode)
+				data, err := os.ReadFile(p.FilesMap["/etc/test/test.conf"].Source)
+				require.NoError(t, err)
+				assert.Equal(t, "key = value\n", string(data))
+				assert.NotContains(t, p.FilesMap, "/var/lib/test/state")
+
+				data, err = os.ReadFile(p.Info.Scripts.PostInstall)
+				require.NoError(t, err)
+				assert.Equal(t, "#!/bin/sh\necho installed\n", string(data))
+				assert.Empty(t, p.Info.RPM.Scripts.PreTrans)
+
+				assert.Equal(t, []string{
+					"rpm %ghost file /var/lib/test/state is not supported for deb",
+					"rpm tag Group is not supported for deb",
+					"rpm scriptlet %pretrans is not supported for deb",
+					"rpm scriptlets are imported as is, check them for rpm specific arguments (number of installed package instances)",
+				}, p.Untranslated)
+
+				require.NoError(t, p.Validate())
+				assert.Equal(t, "misc", p.Info.Section)
+				_, err = p.Do(false)
+				require.NoError(t, err)
+			})
+
+			t.Run("rpm", func(t *testing.T) {
+				p := Packager{InputType: INPUT_RPM, OutputType: RPM, Dir: dir}
+				p.Info.Release = "5"
+				require.NoError(t, p.Init())
+				defer p.Close()
+
+				require.NoError(t, p.AddFiles(StringSlice{path.Base(rpm) + "/var=/var"}))
+
+				assert.Equal(t, "1.2.3", p.Info.Version)
+				assert.Equal(t, "5", p.Info.Release)
+				assert.Equal(t, "Applications/System", p.Info.RPM.Group)
+				assert.Equal(t, []string{"libc6 >= 2.28", "bash"}, p.Info.Depends)
+				assert.Equal(t, []string{"test-api = 1.2"}, p.Info.Provides)
+				assert.Empty(t, p.Untranslated)
+
+				data, err := os.ReadFile(p.Info.RPM.Scripts.PreTrans)
+				require.NoError(t, err)
+				assert.Equal(t, "#!/bin/sh\necho pretrans\n", string(data))
+
+				assert.Len(t, p.FilesMap, 1)
+				assert.Equal(t, ghostStr, p.FilesMap["/var/lib/test/state"].Type)
+			})
+
+			t.Run("types", func(t *testing.T) {
+				p := Packager{InputType: INPUT_RPM, OutputType: RPM, Dir: dir}
+				require.NoError(t, p.Init())
+				defer p.Close()
+
+				require.NoError(t, p.AddFiles(StringSlice{path.Base(rpm)}))
+				require.NoError(t, p.SetTypeRules(nil, false, false))
+
+				// types are set only from rpm file flags
+				for dest, typ := range map[string]string{
+					"/etc/test/test.conf":        configStr,
+					"/etc/test/plain.env":        defaultStr,
+					"/usr/share/doc/test/README": docStr,
+					"/usr/share/doc/test/NOTES":  defaultStr,
+				} {
+					c, ok := p.FilesMap[dest]
+					if assert.Truef(t, ok, "%s not found", dest) {
+						assert.Equal(t, typ, c.Type, dest)
+					}
+				}
+			})
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(path.Join(dir, "test.rpm"), []byte("invalid"), 0644))
+
+		p := Packager{InputType: INPUT_RPM, OutputType: DEB, Dir: dir}
+		require.NoError(t, p.Init())
+		assert.Error(t, p.AddFiles(StringSlice{"test.rpm"}))
+	})
+}
+
+func Test_rpmRelations(t *testing.T) {
+	h := rpmHeader{
+		rpmTagRequireName:    {typ: rpmTypeStringArray, count: 5, data: []byte("a\x00b\x00c\x00rpmlib(PayloadIsZstd)\x00d\x00")},
+		rpmTagRequireVersion: {typ: rpmTypeStringArray, count: 5, data: []byte("\x001.0\x002:1.0-1\x005.4.18-1\x003\x00")},
+		rpmTagRequireFlags: {typ: rpmTypeInt32, count: 5, data: []byte{
+			0, 0, 0, 0,
+			0, 0, 0, rpmSenseGreater | rpmSenseEqual,
+			0, 0, 0, rpmSenseLess,
+			1, 0, 0, rpmSenseLess | rpmSenseEqual,
+			0, 0, 0, rpmSenseEqual,
+		}},
+	}
+	rels, err := rpmRelations(h, rpmTagRequireName, rpmTagRequireVersion, rpmTagRequireFlags)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b >= 1.0", "c < 2:1.0-1", "d = 3"}, rels)
+
+	p := Packager{OutputType: APK}
+	rels, err = p.importRPMRelations("Requires", []string{"b >= 1.0", "/bin/sh", "libc.so.6()(64bit)", "(a or b)", "", " "})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b>=1.0"}, rels)
+	assert.Equal(t, []string{
+		"rpm Requires /bin/sh is not supported for apk",
+		"rpm Requires libc.so.6()(64bit) is not supported for apk",
+		"rpm Requires (a or b) is not supported (rich dependency)",
+		"rpm Requires with empty name is skipped",
+		"rpm Requires with empty name is skipped",
+	}, p.Untranslated)
+}
+
+func Test_readRPMPayloadHardlink(t *testing.T) {
+	var buf bytes.Buffer
+	w := cpio.NewWriter(&buf)
+	for _, hdr := range []*cpio.Header{
+		// data is stored with last link
+		{Name: "./usr/bin/app-hard", Mode: cpio.TypeReg | 0755, Links: 2, Inode: 1},
+		{Name: "./usr/lib/app/app", Mode: cpio.TypeReg | 0755, Links: 2, Inode: 1, Size: 4},
+	} {
+		require.NoError(t, w.WriteHeader(hdr))
+		if hdr.Size > 0 {
+			_, err := w.Write([]byte("bin\n"))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, w.Close())
+
+	p := Packager{InputType: INPUT_RPM, OutputType: DEB}
+	p.FilesMap = make(FileContentMap)
+	defer p.Close()
+
+	// link target is not selected
+	a := newArchiveImporter(&p, FileMap{Src: "test.rpm/usr/bin", Dst: "/usr/bin"}, "usr/bin")
+	rpmFiles := []*rpmFile{
+		{Name: "usr/bin/app-hard", Mode: cpio.TypeReg | 0755, Owner: "root", Group: "root"},
+		{Name: "usr/lib/app/app", Mode: cpio.TypeReg | 0755, Owner: "root", Group: "root"},
+	}
+	require.NoError(t, readRPMPayload(&buf, rpmFiles, a))
+
+	assert.Len(t, p.FilesMap, 1)
+	data, err := os.ReadFile(p.FilesMap["/usr/bin/app-hard"].Source)
+	require.NoError(t, err)
+	assert.Equal(t, "bin\n", string(data))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/goreleaser/nfpm/v2/files"
+)
+
+// addTar add entries from tar archive (may be compressed with gzip, bzip2, xz or zstd)
+func (p *Packager) addTar(fm FileMap) error {
+	return p.addArchives(fm, p.readTar)
+}
+
+func (p *Packager) readTar(name string, a *archiveImporter) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := decompress(f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return readTarEntries(tar.NewReader(r), a)
+}
+
+// readTarEntries add entries from tar stream
+func readTarEntries(tr *tar.Reader, a *archiveImporter) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = readTarEntry(tr, hdr, a); err != nil {
+			return err
+		}
+	}
+}
+
+// readTarEntry add tar entry, file data is read from r
+func readTarEntry(r io.Reader, hdr *tar.Header, a *archiveImporter) error {
+	e := &archiveEntry{
+		Name: hdr.Name,
+		FileInfo: files.ContentFileInfo{
+			Owner: ownerName(hdr.Uname, hdr.Uid),
+			Group: ownerName(hdr.Gname, hdr.Gid),
+			Mode:  os.FileMode(hdr.Mode & 07777),
+			MTime: hdr.ModTime,
+		},
+	}
+	switch hdr.Typeflag {
+	case tar.TypeReg:
+		e.Type = defaultStr
+		// hardlink can point to file outside of mapping source
+		return a.addTarget(e, r)
+	case tar.TypeDir:
+		e.Type = dirStr
+		return a.add(e, nil)
+	case tar.TypeSymlink:
+		e.Type = symlinkStr
+		e.Target = hdr.Linkname
+		e.FileInfo.Mode = 0
+		return a.add(e, nil)
+	case tar.TypeLink:
+		e.Type = defaultStr
+		e.Target = hdr.Linkname
+		return a.addLink(e)
+	case tar.TypeXGlobalHeader:
+		return nil
+	default:
+		return fmt.Errorf("%s: unsupported tar entry type %q", hdr.Name, hdr.Typeflag)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/goreleaser/nfpm/v2/files"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+var testTarMTime = time.Unix(1600000000, 0)
+
+// writeTestTar write tar archive with test entries, compressed with compress
+func writeTestTar(t *testing.T, name, compress string) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Typeflag: tar.TypeDir, Name: "./", Mode: 0755},
+		{Typeflag: tar.TypeDir, Name: "./app-1.0/", Mode: 0750, Uname: "app", Gname: "app"},
+		{Typeflag: tar.TypeReg, Name: "./app-1.0/app", Mode: 04755, Uname: "root", Gname: "wheel", Size: 4},
+		{Typeflag: tar.TypeReg, Name: "./app-1.0/app.conf", Mode: 0640, Uid: 1000, Gid: 1000, Size: 9},
+		{Typeflag: tar.TypeSymlink, Name: "./app-1.0/app-link", Linkname: "app", Mode: 0777},
+		{Typeflag: tar.TypeLink, Name: "./app-1.0/app-hard", Linkname: "./app-1.0/app"},
+		{Typeflag: tar.TypeDir, Name: "./app-1.0/tmp/", Mode: 0755},
+		{Typeflag: tar.TypeReg, Name: "./app-1.0/tmp/file", Mode: 0644, Size: 0},
+		{Typeflag: tar.TypeDir, Name: "./app-1.0/empty/", Mode: 0700},
+	} {
+		hdr.ModTime = testTarMTime
+		require.NoError(t, tw.WriteHeader(hdr))
+		switch hdr.Name {
+		case "./app-1.0/app":
+			_, err := tw.Write([]byte("bin\n"))
+			require.NoError(t, err)
+		case "./app-1.0/app.conf":
+			_, err := tw.Write([]byte("key=val\n\n"))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+
+	var out bytes.Buffer
+	var w io.WriteCloser
+	switch compress {
+	case "gz":
+		w = gzip.NewWriter(&out)
+	case "xz":
+		xw, err := xz.NewWriter(&out)
+		require.NoError(t, err)
+		w = xw
+	case "zst":
+		zw, err := zstd.NewWriter(&out)
+		require.NoError(t, err)
+		w = zw
+	}
+	if w == nil {
+		out = buf
+	} else {
+		_, err := w.Write(buf.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+	require.NoError(t, os.WriteFile(name, out.Bytes(), 0644))
+}
+
+func TestPackage_AddFilesTar(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, compress := range []string{"", "gz", "xz", "zst"} {
+		name := "app.tar"
+		if compress != "" {
+			name += "." + compress
+		}
+		t.Run(name, func(t *testing.T) {
+			writeTestTar(t, path.Join(dir, name), compress)
+
+			p := Packager{InputType: INPUT_TAR, OutputType: RPM, Dir: dir, Exclude: StringSlice{"tmp"}}
+			p.Info.Name = "test"
+			p.Info.Version = "1.0.0"
+			p.Info.Release = "1"
+			p.Info.Arch = "x86_64"
+
+			require.NoError(t, p.Init())
+			defer p.Close()
+
+			err := p.AddFiles(StringSlice{name + "=/opt/"})
+			require.NoError(t, err)
+
+			want := files.Contents{
+				&files.Content{Destination: "/opt/app-1.0/app", Type: defaultStr, FileInfo: &files.ContentFileInfo{Owner: "root", Group: "wheel", Mode: 04755, MTime: testTarMTime}},
+				&files.Content{Destination: "/opt/app-1.0/app.conf", Type: defaultStr, FileInfo: &files.ContentFileInfo{Owner: "1000", Group: "1000", Mode: 0640, MTime: testTarMTime}},
+				&files.Content{Source: "app", Destination: "/opt/app-1.0/app-link", Type: symlinkStr, FileInfo: &files.ContentFileInfo{Owner: "root", Group: "root", MTime: testTarMTime}},
+				&files.Content{Destination: "/opt/app-1.0/app-hard", Type: defaultStr, FileInfo: &files.ContentFileInfo{Owner: "root", Group: "root", MTime: testTarMTime}},
+				&files.Content{Destination: "/opt/app-1.0/empty", Type: dirStr, FileInfo: &files.ContentFileInfo{Owner: "root", Group: "root", Mode: 0700, MTime: testTarMTime}},
+			}
+			data := map[string]string{
+				"/opt/app-1.0/app":      "bin\n",
+				"/opt/app-1.0/app.conf": "key=val\n\n",
+				"/opt/app-1.0/app-hard": "bin\n",
+			}
+			require.Equal(t, len(want), len(p.Info.Contents))
+			for i, c := range p.Info.Contents {
+				if c.Type == defaultStr {
+					b, err := os.ReadFile(c.Source)
+					require.NoError(t, err)
+					assert.Equal(t, data[c.Destination], string(b), c.Destination)
+					want[i].Source = c.Source
+				}
+				c.FileInfo.MTime = c.FileInfo.MTime.Local()
+				want[i].FileInfo.MTime = want[i].FileInfo.MTime.Local()
+				assert.Equal(t, want[i], c)
+			}
+
+			// build package from staged files
+			p.OutDir = t.TempDir()
+			require.NoError(t, p.Validate())
+			_, err = p.Do(false)
+			require.NoError(t, err)
+
+			stageDir := p.stageDir
+			require.NoError(t, p.Close())
+			_, err = os.Stat(stageDir)
+			assert.True(t, os.IsNotExist(err), "stage dir not removed")
+		})
+	}
+}
+
+func TestPackage_AddFilesTarOwner(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTar(t, path.Join(dir, "app.tar.gz"), "gz")
+
+	p := Packager{InputType: INPUT_TAR, OutputType: DEB, Dir: dir, DebUser: "svc"}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+
+	require.NoError(t, p.Init())
+	defer p.Close()
+
+	err := p.AddFiles(StringSlice{"*.tar.gz=/usr/lib:0600::grp"})
+	require.NoError(t, err)
+
+	for dest, want := range map[string]files.ContentFileInfo{
+		"/usr/lib/app-1.0/empty":    {Owner: "svc", Group: "grp", Mode: 0700},
+		"/usr/lib/app-1.0/app":      {Owner: "svc", Group: "grp", Mode: 0600},
+		"/usr/lib/app-1.0/tmp/file": {Owner: "svc", Group: "grp", Mode: 0600},
+	} {
+		c, ok := p.FilesMap[dest]
+		if assert.Truef(t, ok, "%s not found", dest) {
+			assert.Equal(t, want.Owner, c.FileInfo.Owner, dest)
+			assert.Equal(t, want.Group, c.FileInfo.Group, dest)
+			assert.Equal(t, want.Mode, c.FileInfo.Mode, dest)
+		}
+	}
+
+	assert.Error(t, p.AddFiles(StringSlice{"missing.tar"}))
+	assert.Error(t, p.AddFiles(StringSlice{"*.tar.xz"}))
+}
+
+func TestPackage_AddFilesTarHardlink(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTar(t, path.Join(dir, "app.tar.gz"), "gz")
+
+	p := Packager{InputType: INPUT_TAR, OutputType: DEB, Dir: dir, Exclude: StringSlice{"app"}}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+
+	require.NoError(t, p.Init())
+	defer p.Close()
+
+	// hardlink target is outside of prefix and excluded
+	require.NoError(t, p.AddFiles(StringSlice{"app.tar.gz/app-1.0/app-hard=/usr/bin/app-hard"}))
+
+	assert.Len(t, p.FilesMap, 1)
+	data, err := os.ReadFile(p.FilesMap["/usr/bin/app-hard"].Source)
+	require.NoError(t, err)
+	assert.Equal(t, "bin\n", string(data))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// templateFields is a package fields, available in templates
+var templateFields = []string{"Name", "Version", "Iteration", "Release", "Epoch", "Arch", "Platform"}
+
+// templateData return values for templates: package fields and custom values
+func (p *Packager) templateData() map[string]string {
+	arch := p.Info.Arch
+	if p.OutputType == RPM && arch == "amd64" {
+		arch = "x86_64"
+	}
+	data := map[string]string{
+		"Name":      p.Info.Name,
+		"Version":   p.Info.Version,
+		"Iteration": p.Info.Release,
+		"Release":   p.Info.Release,
+		"Epoch":     p.Info.Epoch,
+		"Arch":      arch,
+		"Platform":  p.Info.Platform,
+	}
+	for k, v := range p.templateValues {
+		data[k] = v
+	}
+	return data
+}
+
+func validTemplateKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, c := range key {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// SetTemplateValues set custom template values in format KEY=VALUE (used as {{.KEY}})
+func (p *Packager) SetTemplateValues(values StringSlice) error {
+	p.templateValues = make(map[string]string)
+	for _, s := range values {
+		n := strings.IndexByte(s, '=')
+		if n == -1 || !validTemplateKey(s[:n]) {
+			return fmt.Errorf("template value is invalid: %s", s)
+		}
+		key := s[:n]
+		for _, field := range templateFields {
+			if key == field {
+				return fmt.Errorf("template value is reserved: %s", key)
+			}
+		}
+		p.templateValues[key] = s[n+1:]
+	}
+	return nil
+}
+
+// render execute text/template, missing keys is an error
+func (p *Packager) render(name, text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err = t.Execute(&sb, p.templateData()); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// renderScripts render scripts to dir and replace scripts paths
+func (p *Packager) renderScripts(scripts map[string]*string, dir string) error {
+	for name, script := range scripts {
+		if *script == "" {
+			continue
+		}
+		data, err := os.ReadFile(*script)
+		if err != nil {
+			return err
+		}
+		s, err := p.render(*script, string(data))
+		if err != nil {
+			return err
+		}
+		rendered := path.Join(dir, name)
+		if err = os.WriteFile(rendered, []byte(s), 0755); err != nil {
+			return err
+		}
+		*script = rendered
+	}
+	return nil
+}
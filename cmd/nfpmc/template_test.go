@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackage_SetTemplateValues(t *testing.T) {
+	tests := []struct {
+		values  StringSlice
+		want    map[string]string
+		wantErr bool
+	}{
+		{values: StringSlice{"service=test-svc", "user_name=svc", "empty="}, want: map[string]string{"service": "test-svc", "user_name": "svc", "empty": ""}},
+		{values: StringSlice{"url=http://host/?a=b"}, want: map[string]string{"url": "http://host/?a=b"}},
+		{values: StringSlice{"service"}, wantErr: true},
+		{values: StringSlice{"=value"}, wantErr: true},
+		{values: StringSlice{"1key=value"}, wantErr: true},
+		{values: StringSlice{"my-key=value"}, wantErr: true},
+		{values: StringSlice{"Version=2.0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.values.String(), func(t *testing.T) {
+			var p Packager
+			err := p.SetTemplateValues(tt.values)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, p.templateValues)
+			}
+		})
+	}
+}
+
+func TestPackage_render(t *testing.T) {
+	p := Packager{OutputType: RPM}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "2"
+	p.Info.Arch = "amd64"
+	require.NoError(t, p.SetTemplateValues(StringSlice{"service=test-svc"}))
+
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{text: "/opt/test", want: "/opt/test"},
+		{text: "/opt/{{.Name}}-{{.Version}}-{{.Iteration}}.{{.Arch}}/", want: "/opt/test-1.0.0-2.x86_64/"},
+		{text: "/etc/{{.service}}.conf", want: "/etc/test-svc.conf"},
+		{text: "/etc/{{.unknown}}.conf", wantErr: true},
+		{text: "/etc/{{.service", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := p.render("test", tt.text)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPackage_AddFilesTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, "test.conf"), []byte("key = value\n"), 0644))
+
+	p := Packager{OutputType: DEB, Dir: dir}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	p.Info.Release = "1"
+	p.Info.Arch = "amd64"
+
+	require.NoError(t, p.Init())
+	require.NoError(t, p.SetTemplateValues(StringSlice{"service=svc"}))
+	require.NoError(t, p.AddFiles(StringSlice{"test.conf=/etc/{{.Name}}/{{.service}}.conf"}))
+	require.NoError(t, p.AddSymlinks(StringSlice{"/etc/test/svc.conf=/etc/{{.Name}}.conf"}))
+
+	assert.Contains(t, p.FilesMap, "/etc/test/svc.conf")
+	assert.Contains(t, p.FilesMap, "/etc/test.conf")
+
+	assert.Error(t, p.AddFiles(StringSlice{"test.conf=/etc/{{.missing}}.conf"}))
+}
+
+func TestPackage_AddFilesTemplateVersionRelease(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, "test.conf"), []byte("key = value\n"), 0644))
+
+	p := Packager{OutputType: DEB, Dir: dir}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0-2"
+	p.Info.Release = "0"
+	p.Info.Arch = "amd64"
+
+	require.NoError(t, p.Init())
+	require.NoError(t, p.AddFiles(StringSlice{"test.conf=/opt/{{.Name}}-{{.Version}}-{{.Iteration}}/test.conf"}))
+	require.NoError(t, p.Validate())
+
+	// destinations and scripts are rendered with the same values
+	assert.Contains(t, p.FilesMap, "/opt/test-1.0.0-2/test.conf")
+	got, err := p.render("script", "{{.Version}}-{{.Iteration}}")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0-2", got)
+}
+
+func TestPackage_renderScripts(t *testing.T) {
+	srcDir := t.TempDir()
+	postinstall := path.Join(srcDir, "postinstall.sh")
+	require.NoError(t, os.WriteFile(postinstall, []byte("#!/bin/sh\nsystemctl enable {{.service}}\necho {{.Name}} {{.Version}}\n"), 0644))
+	preremove := path.Join(srcDir, "preremove.sh")
+	require.NoError(t, os.WriteFile(preremove, []byte("#!/bin/sh\nsystemctl disable {{.missing}}\n"), 0644))
+
+	p := Packager{OutputType: DEB}
+	p.Info.Name = "test"
+	p.Info.Version = "1.0.0"
+	require.NoError(t, p.SetTemplateValues(StringSlice{"service=svc"}))
+
+	dir := t.TempDir()
+	var empty string
+	script := postinstall
+	err := p.renderScripts(map[string]*string{"postinstall": &script, "preinstall": &empty}, dir)
+	require.NoError(t, err)
+	assert.Equal(t, path.Join(dir, "postinstall"), script)
+	assert.Equal(t, "", empty)
+	data, err := os.ReadFile(script)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\nsystemctl enable svc\necho test 1.0.0\n", string(data))
+
+	script = preremove
+	err = p.renderScripts(map[string]*string{"preremove": &script}, dir)
+	assert.Error(t, err)
+	assert.Equal(t, preremove, script)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	licenseStr = "license"
+	readmeStr  = "readme"
+)
+
+// TypeRule set content type for files with destination matched by pattern
+type TypeRule struct {
+	Pattern string
+	Type    string
+}
+
+// defaultTypeRules is applied after user rules
+var defaultTypeRules = []TypeRule{
+	{Pattern: "/etc/**", Type: configStr},
+	{Pattern: "/usr/share/doc/**", Type: docStr},
+	{Pattern: "/usr/share/man/**", Type: docStr},
+	{Pattern: "/usr/share/info/**", Type: docStr},
+	{Pattern: "/usr/share/licenses/**", Type: licenseStr},
+}
+
+// typeRuleTypes map rule type names to content types
+var typeRuleTypes = map[string]string{
+	"file":             defaultStr,
+	"config":           configReplaceStr,
+	"config|noreplace": configStr,
+	"ghost":            ghostStr,
+	"doc":              docStr,
+	"license":          licenseStr,
+	"licence":          licenseStr,
+	"readme":           readmeStr,
+}
+
+// parseTypeRule parse rule in format PATTERN=TYPE
+func parseTypeRule(s string) (TypeRule, error) {
+	n := strings.LastIndexByte(s, '=')
+	if n < 1 {
+		return TypeRule{}, fmt.Errorf("type rule is invalid: %s", s)
+	}
+	typ, ok := typeRuleTypes[s[n+1:]]
+	if !ok {
+		return TypeRule{}, fmt.Errorf("type rule is invalid: %s: unknown type %s", s, s[n+1:])
+	}
+	rule := TypeRule{Pattern: s[:n], Type: typ}
+	if err := validatePattern(rule.Pattern); err != nil {
+		return TypeRule{}, fmt.Errorf("type rule is invalid: %s: %s", s, err.Error())
+	}
+	return rule, nil
+}
+
+// SetTypeRules set type for files without type by destination rules (PATTERN=TYPE), first matched rule wins.
+// User rules are checked before default rules. Default config and doc rules are skipped when config or doc files are set explicitly.
+// Default rules are not applied for package and nfpm config inputs, types are imported from package (or config).
+func (p *Packager) SetTypeRules(rules StringSlice, explicitConfig, explicitDoc bool) error {
+	typeRules := make([]TypeRule, 0, len(rules)+len(defaultTypeRules))
+	for _, s := range rules {
+		rule, err := parseTypeRule(s)
+		if err != nil {
+			return err
+		}
+		typeRules = append(typeRules, rule)
+	}
+	if !p.NoDefaultTypeRules && !p.InputType.IsPackage() && p.InputType != INPUT_NFPM {
+		noConfig := explicitConfig || (p.DebNoDefaultConfigFiles && p.OutputType == DEB)
+		for _, rule := range defaultTypeRules {
+			if (rule.Type == configStr && noConfig) || (rule.Type == docStr && explicitDoc) {
+				continue
+			}
+			typeRules = append(typeRules, rule)
+		}
+	}
+
+	for dest, c := range p.FilesMap {
+		if c.Type != defaultStr {
+			continue
+		}
+		for _, rule := range typeRules {
+			ok, err := matchPath(rule.Pattern, dest)
+			if err != nil {
+				return err
+			}
+			if ok {
+				c.Type = p.contentType(rule.Type)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// contentType return type for output, doc, license and readme are rpm only types (other packagers skip such files)
+func (p *Packager) contentType(typ string) string {
+	if p.OutputType != RPM && (typ == docStr || typ == licenseStr || typ == readmeStr) {
+		return defaultStr
+	}
+	return typ
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/goreleaser/nfpm/v2/files"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseTypeRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    TypeRule
+		wantErr bool
+	}{
+		{rule: "/usr/share/doc/**=doc", want: TypeRule{Pattern: "/usr/share/doc/**", Type: docStr}},
+		{rule: "/etc/**=config", want: TypeRule{Pattern: "/etc/**", Type: configReplaceStr}},
+		{rule: "/etc/**=config|noreplace", want: TypeRule{Pattern: "/etc/**", Type: configStr}},
+		{rule: "/etc/a=b/**=file", want: TypeRule{Pattern: "/etc/a=b/**", Type: defaultStr}},
+		{rule: "*.md=readme", want: TypeRule{Pattern: "*.md", Type: readmeStr}},
+		{rule: "/etc/**", wantErr: true},
+		{rule: "=doc", wantErr: true},
+		{rule: "/etc/**=symlink", wantErr: true},
+		{rule: "/etc/[=config", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := parseTypeRule(tt.rule)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPackage_SetTypeRules(t *testing.T) {
+	dests := []string{
+		"/etc/test/test.conf",
+		"/etc/test/test.env",
+		"/usr/share/doc/test/README",
+		"/usr/share/man/man1/test.1.gz",
+		"/usr/share/licenses/test/LICENSE",
+		"/usr/share/icons/test.png",
+		"/usr/share/locale/ru/LC_MESSAGES/test.mo",
+		"/usr/bin/test",
+	}
+	tests := []struct {
+		name           string
+		inputType      InputType
+		outputType     OutputType
+		rules          StringSlice
+		noDefault      bool
+		debNoConfig    bool
+		explicitConfig bool
+		explicitDoc    bool
+		preset         map[string]string
+		want           map[string]string
+	}{
+		{
+			name:       "rpm defaults",
+			outputType: RPM,
+			want: map[string]string{
+				"/etc/test/test.conf":              configStr,
+				"/etc/test/test.env":               configStr,
+				"/usr/share/doc/test/README":       docStr,
+				"/usr/share/man/man1/test.1.gz":    docStr,
+				"/usr/share/licenses/test/LICENSE": licenseStr,
+			},
+		},
+		{
+			name:       "deb defaults",
+			outputType: DEB,
+			want: map[string]string{
+				"/etc/test/test.conf": configStr,
+				"/etc/test/test.env":  configStr,
+			},
+		},
+		{
+			name:        "deb no default config files",
+			outputType:  DEB,
+			debNoConfig: true,
+			want:        map[string]string{},
+		},
+		{
+			name:        "rpm ignores deb no default config files",
+			outputType:  RPM,
+			debNoConfig: true,
+			rules:       StringSlice{"/usr/share/**=file"},
+			want: map[string]string{
+				"/etc/test/test.conf": configStr,
+				"/etc/test/test.env":  configStr,
+			},
+		},
+		{
+			name:       "user rules before defaults",
+			outputType: RPM,
+			rules:      StringSlice{"/etc/**/*.env=config", "/usr/share/icons/**=ghost"},
+			want: map[string]string{
+				"/etc/test/test.conf":              configStr,
+				"/etc/test/test.env":               configReplaceStr,
+				"/usr/share/doc/test/README":       docStr,
+				"/usr/share/man/man1/test.1.gz":    docStr,
+				"/usr/share/licenses/test/LICENSE": licenseStr,
+				"/usr/share/icons/test.png":        ghostStr,
+			},
+		},
+		{
+			name:       "no default rules",
+			outputType: RPM,
+			rules:      StringSlice{"*.mo=doc"},
+			noDefault:  true,
+			want: map[string]string{
+				"/usr/share/locale/ru/LC_MESSAGES/test.mo": docStr,
+			},
+		},
+		{
+			name:       "package input",
+			inputType:  INPUT_DEB,
+			outputType: RPM,
+			rules:      StringSlice{"*.mo=doc"},
+			want: map[string]string{
+				"/usr/share/locale/ru/LC_MESSAGES/test.mo": docStr,
+			},
+		},
+		{
+			name:           "explicit config and doc files",
+			outputType:     RPM,
+			explicitConfig: true,
+			explicitDoc:    true,
+			preset:         map[string]string{"/etc/test/test.conf": configStr},
+			want: map[string]string{
+				"/etc/test/test.conf":              configStr,
+				"/usr/share/licenses/test/LICENSE": licenseStr,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Packager{InputType: tt.inputType, OutputType: tt.outputType, NoDefaultTypeRules: tt.noDefault, DebNoDefaultConfigFiles: tt.debNoConfig}
+			p.FilesMap = make(FileContentMap)
+			for _, dest := range dests {
+				p.FilesMap[dest] = &files.Content{Source: "src", Destination: dest, Type: tt.preset[dest]}
+			}
+
+			err := p.SetTypeRules(tt.rules, tt.explicitConfig, tt.explicitDoc)
+			assert.NoError(t, err)
+
+			got := make(map[string]string)
+			for dest, c := range p.FilesMap {
+				if c.Type != defaultStr {
+					got[dest] = c.Type
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/goreleaser/nfpm/v2/files"
+)
+
+// addZip add entries from zip archive
+func (p *Packager) addZip(fm FileMap) error {
+	return p.addArchives(fm, p.readZip)
+}
+
+func (p *Packager) readZip(name string, a *archiveImporter) error {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if err = readZipEntry(f, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zip creator host (high byte of CreatorVersion), other hosts has no unix mode in external attributes
+const (
+	zipCreatorUnix  = 3
+	zipCreatorMacOS = 19
+)
+
+// zipMode return permission bits from zip entry: unix mode from external attributes or 0644 (0755 for dirs)
+func zipMode(f *zip.File) os.FileMode {
+	mode := f.Mode()
+	if creator := f.CreatorVersion >> 8; creator != zipCreatorUnix && creator != zipCreatorMacOS {
+		if mode.IsDir() {
+			return 0755
+		}
+		return 0644
+	}
+	perm := mode.Perm()
+	if mode&os.ModeSetuid != 0 {
+		perm |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		perm |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		perm |= 01000
+	}
+	return perm
+}
+
+func readZipEntry(f *zip.File, a *archiveImporter) error {
+	mode := f.Mode()
+	e := &archiveEntry{
+		Name:     f.Name,
+		FileInfo: files.ContentFileInfo{Mode: zipMode(f), MTime: f.Modified},
+	}
+	switch {
+	case mode.IsDir():
+		e.Type = dirStr
+		return a.add(e, nil)
+	case mode&os.ModeSymlink != 0:
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		target, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		e.Type = symlinkStr
+		e.Target = string(target)
+		e.FileInfo.Mode = 0
+		return a.add(e, nil)
+	case mode.IsRegular():
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		e.Type = defaultStr
+		return a.add(e, r)
+	default:
+		return fmt.Errorf("%s: unsupported zip entry mode %s", f.Name, mode)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestZip write zip archive with test entries
+func writeTestZip(t *testing.T, name string) {
+	f, err := os.Create(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, e := range []struct {
+		name string
+		mode os.FileMode
+		data string
+		fat  bool
+	}{
+		{name: "vendor-1.2/", mode: os.ModeDir | 0755},
+		{name: "vendor-1.2/bin/", mode: os.ModeDir | 0755},
+		{name: "vendor-1.2/bin/tool", mode: os.ModeSetuid | 0755, data: "tool\n"},
+		{name: "vendor-1.2/bin/tool-link", mode: os.ModeSymlink | 0777, data: "tool"},
+		{name: "vendor-1.2/etc/app.conf", mode: 0640, data: "key=val\n"},
+		{name: "vendor-1.2/share/README.txt", data: "readme\n", fat: true},
+		{name: "vendor-1.2/share/empty/", mode: os.ModeDir | 0700},
+	} {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.fat {
+			// msdos attributes, read-only is not set
+			hdr.CreatorVersion = 0
+			hdr.ExternalAttrs = 0x20
+		} else {
+			hdr.SetMode(e.mode)
+		}
+		w, err := zw.CreateHeader(hdr)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(e.data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+}
+
+func TestPackage_AddFilesZip(t *testing.T) {
+	dir := t.TempDir()
+	writeTestZip(t, path.Join(dir, "bundle.zip"))
+
+	tests := []struct {
+		name  string
+		files StringSlice
+		// want is a destination => type:mode
+		want map[string]string
+		// data is a file contents by destination
+		data map[string]string
+	}{
+		{
+			name:  "archive",
+			files: StringSlice{"bundle.zip=/opt/vendor"},
+			want: map[string]string{
+				"/opt/vendor/vendor-1.2/bin/tool":         ":4755",
+				"/opt/vendor/vendor-1.2/bin/tool-link":    "symlink:0000",
+				"/opt/vendor/vendor-1.2/etc/app.conf":     ":0640",
+				"/opt/vendor/vendor-1.2/share/README.txt": ":0644",
+				"/opt/vendor/vendor-1.2/share/empty":      "dir:0700",
+			},
+			data: map[string]string{
+				"/opt/vendor/vendor-1.2/bin/tool":         "tool\n",
+				"/opt/vendor/vendor-1.2/etc/app.conf":     "key=val\n",
+				"/opt/vendor/vendor-1.2/share/README.txt": "readme\n",
+			},
+		},
+		{
+			name:  "prefix",
+			files: StringSlice{"bundle.zip/vendor-1.2/bin=/usr/bin", "bundle.zip/vendor-1.2/etc/app.conf=/etc/vendor/vendor.conf"},
+			want: map[string]string{
+				"/usr/bin/tool":           ":4755",
+				"/usr/bin/tool-link":      "symlink:0000",
+				"/etc/vendor/vendor.conf": ":0640",
+			},
+			data: map[string]string{
+				"/usr/bin/tool":           "tool\n",
+				"/etc/vendor/vendor.conf": "key=val\n",
+			},
+		},
+		{
+			name:  "glob prefix",
+			files: StringSlice{"bundle.zip/vendor-*/share=/usr/share/vendor"},
+			want: map[string]string{
+				"/usr/share/vendor/share/README.txt": ":0644",
+				"/usr/share/vendor/share/empty":      "dir:0700",
+			},
+		},
+		{
+			name:  "prefix without destination",
+			files: StringSlice{"bundle.zip/vendor-1.2/etc"},
+			want: map[string]string{
+				"/opt/vendor-1.2/etc/app.conf": ":0640",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Packager{InputType: INPUT_ZIP, OutputType: RPM, Dir: dir, Prefix: "/opt"}
+			p.Info.Name = "test"
+			p.Info.Version = "1.0.0"
+			p.Info.Release = "1"
+
+			require.NoError(t, p.Init())
+			defer p.Close()
+
+			err := p.AddFiles(tt.files)
+			require.NoError(t, err)
+
+			got := make(map[string]string)
+			for dest, c := range p.FilesMap {
+				got[dest] = fmt.Sprintf("%s:%04o", c.Type, c.FileInfo.Mode)
+				if want, ok := tt.data[dest]; ok {
+					b, err := os.ReadFile(c.Source)
+					require.NoError(t, err)
+					assert.Equal(t, want, string(b), dest)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}